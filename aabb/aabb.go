@@ -24,6 +24,15 @@ func (a *AABB) Expand(x, y int32) {
 	}
 }
 
+// Union returns the smallest AABB holding both a and b
+func (a *AABB) Union(b *AABB) AABB {
+	u := *a
+	u.Expand(b.X0, b.Y0)
+	u.Expand(b.X1, b.Y1)
+
+	return u
+}
+
 // TestCollision checks for an AABB collision. TODO: something more mathy and
 // faster?
 func (a *AABB) TestCollision(b *AABB) bool {
@@ -0,0 +1,307 @@
+// Package collision finds overlapping bodies with a uniform grid broadphase,
+// and tells them when they start touching, keep touching, and stop.
+//
+// Bodies are sorted into layers, and each has a mask of the layers it wants
+// to hear about. Two bodies are checked against each other only if at least
+// one of them is interested in the other's layer.
+package collision
+
+import "github.com/beejjorgensen/eggdrop/aabb"
+
+// DefaultCellSize is a reasonable grid cell size for things around the size
+// of an egg, in px
+const DefaultCellSize = 64
+
+// Body is anything that can be in a World. The bounds are in world
+// coordinates. Inactive bodies are ignored, and any contacts they had end.
+type Body interface {
+	CollisionBounds() aabb.AABB
+	CollisionActive() bool
+}
+
+// Handlers are called with the body they were registered for and the other
+// body in the contact. Any of them may be nil.
+type Handlers struct {
+	Enter, Stay, Exit func(self, other Body)
+}
+
+// body is a Body in a World
+type body struct {
+	Body
+	id          uint32
+	layer, mask uint32
+	handlers    Handlers
+	bounds      aabb.AABB // as of this step
+}
+
+// wants reports whether b cares about contacts with other
+func (b *body) wants(other *body) bool {
+	return b.mask&other.layer != 0
+}
+
+// pairKey identifies a contact, lower body ID first
+type pairKey struct {
+	a, b uint32
+}
+
+// pair is two bodies found touching this step
+type pair struct {
+	key  pairKey
+	a, b *body
+}
+
+// contact is an ongoing contact between two bodies
+type contact struct {
+	a, b *body
+	step uint32 // last step they were touching
+}
+
+// cellKey is a grid cell's coordinates
+type cellKey struct {
+	x, y int32
+}
+
+// World is a collection of bodies to test against each other
+type World struct {
+	CellSize int32
+
+	bodies []*body
+	byBody map[Body]*body
+	nextID uint32
+
+	cells    map[cellKey][]*body
+	used     []cellKey // cells with anything in them this step
+	pairs    []pair    // pairs found this step
+	contacts map[pairKey]contact
+	step     uint32
+}
+
+// NewWorld makes an empty World with the given grid cell size in px
+func NewWorld(cellSize int32) *World {
+	return &World{
+		CellSize: cellSize,
+		byBody:   make(map[Body]*body),
+		cells:    make(map[cellKey][]*body),
+		contacts: make(map[pairKey]contact),
+	}
+}
+
+// Add puts a body in the world on the given layer, interested in the layers
+// in mask. Adding a body that's already there updates it.
+func (w *World) Add(b Body, layer, mask uint32, handlers Handlers) {
+	if existing, ok := w.byBody[b]; ok {
+		existing.layer = layer
+		existing.mask = mask
+		existing.handlers = handlers
+		return
+	}
+
+	w.nextID++
+
+	bi := &body{Body: b, id: w.nextID, layer: layer, mask: mask, handlers: handlers}
+
+	w.bodies = append(w.bodies, bi)
+	w.byBody[b] = bi
+}
+
+// Remove takes a body out of the world. Its contacts are forgotten without
+// any Exit calls.
+func (w *World) Remove(b Body) {
+	bi, ok := w.byBody[b]
+	if !ok {
+		return
+	}
+
+	delete(w.byBody, b)
+
+	for i, other := range w.bodies {
+		if other == bi {
+			last := len(w.bodies) - 1
+			w.bodies[i] = w.bodies[last]
+			w.bodies[last] = nil
+			w.bodies = w.bodies[:last]
+			break
+		}
+	}
+
+	for key, c := range w.contacts {
+		if c.a == bi || c.b == bi {
+			delete(w.contacts, key)
+		}
+	}
+}
+
+// Len returns the number of bodies in the world
+func (w *World) Len() int {
+	return len(w.bodies)
+}
+
+// cellRange returns the cells an AABB covers
+func (w *World) cellRange(box aabb.AABB) (x0, y0, x1, y1 int32) {
+	return floorDiv(box.X0, w.CellSize), floorDiv(box.Y0, w.CellSize),
+		floorDiv(box.X1, w.CellSize), floorDiv(box.Y1, w.CellSize)
+}
+
+// floorDiv divides, rounding toward negative infinity so cells left of and
+// above the origin work out
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+
+	return q
+}
+
+// buildGrid sorts the active bodies into the cells they cover. The cell
+// slices are kept between steps so this doesn't allocate once things settle.
+func (w *World) buildGrid() {
+	for _, key := range w.used {
+		w.cells[key] = w.cells[key][:0]
+	}
+	w.used = w.used[:0]
+
+	for _, b := range w.bodies {
+		if !b.CollisionActive() {
+			continue
+		}
+
+		b.bounds = b.CollisionBounds()
+
+		x0, y0, x1, y1 := w.cellRange(b.bounds)
+
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				key := cellKey{x, y}
+				cell := w.cells[key]
+
+				if len(cell) == 0 {
+					w.used = append(w.used, key)
+				}
+
+				w.cells[key] = append(cell, b)
+			}
+		}
+	}
+}
+
+// findPairs collects the pairs of bodies that overlap and care about each
+// other. A pair that shares several cells is only counted in the one holding
+// the top left corner of their overlap.
+func (w *World) findPairs() {
+	w.pairs = w.pairs[:0]
+
+	for _, key := range w.used {
+		cell := w.cells[key]
+
+		for i, a := range cell {
+			for _, b := range cell[i+1:] {
+				if !a.wants(b) && !b.wants(a) {
+					continue
+				}
+
+				if !a.bounds.TestCollision(&b.bounds) {
+					continue
+				}
+
+				cx := floorDiv(max32(a.bounds.X0, b.bounds.X0), w.CellSize)
+				cy := floorDiv(max32(a.bounds.Y0, b.bounds.Y0), w.CellSize)
+				if cx != key.x || cy != key.y {
+					continue
+				}
+
+				if a.id > b.id {
+					a, b = b, a
+				}
+
+				w.pairs = append(w.pairs, pair{pairKey{a.id, b.id}, a, b})
+			}
+		}
+	}
+}
+
+// max32 returns the larger of two int32s
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// notify calls a handler for each side of a contact that's interested
+func notify(a, b *body, which func(h *Handlers) func(self, other Body)) {
+	if f := which(&a.handlers); f != nil && a.wants(b) {
+		f(a.Body, b.Body)
+	}
+
+	if f := which(&b.handlers); f != nil && b.wants(a) {
+		f(b.Body, a.Body)
+	}
+}
+
+func enterHandler(h *Handlers) func(self, other Body) { return h.Enter }
+func stayHandler(h *Handlers) func(self, other Body)  { return h.Stay }
+func exitHandler(h *Handlers) func(self, other Body)  { return h.Exit }
+
+// Step finds everything that's touching and calls the Enter, Stay and Exit
+// handlers. Handlers can deactivate bodies (e.g. hide a caught egg), which
+// ends their contacts right away. Handlers mustn't Add or Remove bodies.
+func (w *World) Step() {
+	w.step++
+
+	w.buildGrid()
+	w.findPairs()
+
+	for _, p := range w.pairs {
+		// A handler might have taken one of them out already
+		if !p.a.CollisionActive() || !p.b.CollisionActive() {
+			continue
+		}
+
+		_, ongoing := w.contacts[p.key]
+		c := contact{a: p.a, b: p.b, step: w.step}
+		w.contacts[p.key] = c
+
+		if ongoing {
+			notify(c.a, c.b, stayHandler)
+		} else {
+			notify(c.a, c.b, enterHandler)
+		}
+
+		// If the handlers took one out, the contact's over now, rather than
+		// next step when it might be back somewhere else
+		if !p.a.CollisionActive() || !p.b.CollisionActive() {
+			delete(w.contacts, p.key)
+			notify(c.a, c.b, exitHandler)
+		}
+	}
+
+	for key, c := range w.contacts {
+		if c.step != w.step {
+			delete(w.contacts, key)
+			notify(c.a, c.b, exitHandler)
+		}
+	}
+}
+
+// Query calls fn for each active body whose bounds overlap box and whose
+// layer is in mask, as of the last Step. Returning false stops the search.
+// A body covering several cells may be seen more than once.
+func (w *World) Query(box aabb.AABB, mask uint32, fn func(b Body) bool) {
+	x0, y0, x1, y1 := w.cellRange(box)
+
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			for _, b := range w.cells[cellKey{x, y}] {
+				if b.layer&mask == 0 || !b.bounds.TestCollision(&box) {
+					continue
+				}
+
+				if !fn(b.Body) {
+					return
+				}
+			}
+		}
+	}
+}
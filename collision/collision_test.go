@@ -0,0 +1,406 @@
+package collision
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/beejjorgensen/eggdrop/aabb"
+)
+
+// Hailstorm benchmarks: lots of small things falling down the screen past a
+// nest, the way eggs do. Each hailstone only watches for the nest, so most of
+// the grid's work is sorting out which pairs nobody cares about.
+
+const (
+	stormW = 800 // px
+	stormH = 600 // px
+
+	hailSize  = 16 // px
+	hailSpeed = 7  // px per step
+)
+
+const (
+	layerHail = 1 << iota
+	layerNest
+)
+
+// box is a Body for the benchmarks
+type box struct {
+	aabb.AABB
+}
+
+func (b *box) CollisionBounds() aabb.AABB { return b.AABB }
+func (b *box) CollisionActive() bool      { return true }
+
+// fall moves a hailstone down, wrapping back to the top
+func (b *box) fall() {
+	b.Y0 += hailSpeed
+	if b.Y0 > stormH {
+		b.Y0 -= stormH + hailSize
+	}
+	b.Y1 = b.Y0 + hailSize
+}
+
+// newStorm makes n hailstones scattered over the screen and a nest at the
+// bottom
+func newStorm(n int) (hail []*box, nest *box) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < n; i++ {
+		x := rng.Int31n(stormW - hailSize)
+		y := rng.Int31n(stormH)
+		hail = append(hail, &box{aabb.AABB{X0: x, Y0: y, X1: x + hailSize, Y1: y + hailSize}})
+	}
+
+	nest = &box{aabb.AABB{X0: 300, Y0: 540, X1: 500, Y1: 590}}
+
+	return hail, nest
+}
+
+var stormSizes = []int{1000, 5000, 10000}
+
+// BenchmarkHailstormGrid steps a World with the grid broadphase
+func BenchmarkHailstormGrid(b *testing.B) {
+	for _, n := range stormSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			hail, nest := newStorm(n)
+
+			var caught, escaped int
+			handlers := Handlers{
+				Enter: func(self, other Body) { caught++ },
+				Exit:  func(self, other Body) { escaped++ },
+			}
+
+			w := NewWorld(DefaultCellSize)
+			w.Add(nest, layerNest, layerHail, Handlers{})
+			for _, h := range hail {
+				w.Add(h, layerHail, layerNest, handlers)
+			}
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				for _, h := range hail {
+					h.fall()
+				}
+				w.Step()
+			}
+		})
+	}
+}
+
+// BenchmarkHailstormBruteForce tests every pair, for comparison. It keeps
+// track of contacts and calls Enter and Exit the same way a World does, so
+// only the broadphase differs. The biggest storm is left out since it takes
+// forever.
+func BenchmarkHailstormBruteForce(b *testing.B) {
+	for _, n := range stormSizes[:2] {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			hail, nest := newStorm(n)
+
+			var caught, escaped int
+			handlers := Handlers{
+				Enter: func(self, other Body) { caught++ },
+				Exit:  func(self, other Body) { escaped++ },
+			}
+
+			var bodies []*body
+			bodies = append(bodies, &body{Body: nest, id: 1, layer: layerNest, mask: layerHail})
+			for i, h := range hail {
+				bodies = append(bodies, &body{Body: h, id: uint32(i + 2), layer: layerHail, mask: layerNest, handlers: handlers})
+			}
+
+			contacts := make(map[pairKey]contact)
+			var step uint32
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				step++
+
+				for _, h := range hail {
+					h.fall()
+				}
+
+				for _, bi := range bodies {
+					bi.bounds = bi.CollisionBounds()
+				}
+
+				for j, a := range bodies {
+					for _, o := range bodies[j+1:] {
+						if !a.wants(o) && !o.wants(a) {
+							continue
+						}
+
+						if !a.bounds.TestCollision(&o.bounds) {
+							continue
+						}
+
+						key := pairKey{a.id, o.id}
+						_, ongoing := contacts[key]
+						contacts[key] = contact{a: a, b: o, step: step}
+
+						if !ongoing {
+							notify(a, o, enterHandler)
+						}
+					}
+				}
+
+				for key, c := range contacts {
+					if c.step != step {
+						delete(contacts, key)
+						notify(c.a, c.b, exitHandler)
+					}
+				}
+			}
+		})
+	}
+}
+
+// testBody is a Body that can be switched off
+type testBody struct {
+	name   string
+	box    aabb.AABB
+	active bool
+}
+
+func (b *testBody) CollisionBounds() aabb.AABB { return b.box }
+func (b *testBody) CollisionActive() bool      { return b.active }
+
+// newTestBody makes an active body
+func newTestBody(name string, x0, y0, x1, y1 int32) *testBody {
+	return &testBody{name: name, box: aabb.AABB{X0: x0, Y0: y0, X1: x1, Y1: y1}, active: true}
+}
+
+// recorder makes handlers that log what they're called with
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) handlers() Handlers {
+	log := func(kind string) func(self, other Body) {
+		return func(self, other Body) {
+			r.events = append(r.events, fmt.Sprintf("%s %s %s", kind, self.(*testBody).name, other.(*testBody).name))
+		}
+	}
+
+	return Handlers{Enter: log("enter"), Stay: log("stay"), Exit: log("exit")}
+}
+
+// take returns the events so far and forgets them
+func (r *recorder) take() []string {
+	events := r.events
+	r.events = nil
+
+	return events
+}
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct {
+		a, b, want int32
+	}{
+		{0, 64, 0},
+		{7, 64, 0},
+		{63, 64, 0},
+		{64, 64, 1},
+		{-1, 64, -1},
+		{-63, 64, -1},
+		{-64, 64, -1},
+		{-65, 64, -2},
+		{-128, 64, -2},
+	}
+
+	for _, tt := range tests {
+		if got := floorDiv(tt.a, tt.b); got != tt.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindPairsOncePerPair(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b aabb.AABB
+		want int
+	}{
+		{"same cell", aabb.AABB{X0: 1, Y0: 1, X1: 20, Y1: 20}, aabb.AABB{X0: 10, Y0: 10, X1: 30, Y1: 30}, 1},
+		{"sharing four cells", aabb.AABB{X0: 10, Y0: 10, X1: 200, Y1: 200}, aabb.AABB{X0: 50, Y0: 50, X1: 150, Y1: 150}, 1},
+		{"overlap starts in a later cell", aabb.AABB{X0: 10, Y0: 10, X1: 200, Y1: 200}, aabb.AABB{X0: 100, Y0: 130, X1: 300, Y1: 300}, 1},
+		{"negative coordinates", aabb.AABB{X0: -200, Y0: -200, X1: -10, Y1: -10}, aabb.AABB{X0: -150, Y0: -100, X1: 50, Y1: 50}, 1},
+		{"straddling the origin", aabb.AABB{X0: -70, Y0: -70, X1: 70, Y1: 70}, aabb.AABB{X0: -5, Y0: -5, X1: 5, Y1: 5}, 1},
+		{"neighboring cells, apart", aabb.AABB{X0: 1, Y0: 1, X1: 60, Y1: 60}, aabb.AABB{X0: 70, Y0: 1, X1: 120, Y1: 60}, 0},
+	}
+
+	for _, tt := range tests {
+		w := NewWorld(64)
+		w.Add(&testBody{name: "a", box: tt.a, active: true}, 1, 1, Handlers{})
+		w.Add(&testBody{name: "b", box: tt.b, active: true}, 1, 1, Handlers{})
+
+		w.buildGrid()
+		w.findPairs()
+
+		if len(w.pairs) != tt.want {
+			t.Errorf("%s: found %d pairs, want %d", tt.name, len(w.pairs), tt.want)
+		}
+	}
+}
+
+func TestContactSequence(t *testing.T) {
+	var r recorder
+
+	a := newTestBody("a", 0, 0, 50, 50)
+	b := newTestBody("b", 200, 10, 250, 60)
+
+	w := NewWorld(64)
+	w.Add(a, 1, 1, r.handlers())
+	w.Add(b, 1, 1, r.handlers())
+
+	steps := []struct {
+		bx   int32 // where b moves before the step
+		want []string
+	}{
+		{200, nil},
+		{40, []string{"enter a b", "enter b a"}},
+		{30, []string{"stay a b", "stay b a"}},
+		{20, []string{"stay a b", "stay b a"}},
+		{200, []string{"exit a b", "exit b a"}},
+		{200, nil},
+		{10, []string{"enter a b", "enter b a"}},
+	}
+
+	for i, s := range steps {
+		b.box.X0, b.box.X1 = s.bx, s.bx+50
+
+		w.Step()
+
+		if got := r.take(); !reflect.DeepEqual(got, s.want) {
+			t.Errorf("step %d: events %q, want %q", i, got, s.want)
+		}
+	}
+}
+
+func TestMasks(t *testing.T) {
+	const (
+		layerA = 1 << iota
+		layerB
+		layerC
+	)
+
+	tests := []struct {
+		name         string
+		maskA, maskB uint32
+		want         []string
+	}{
+		{"both watch", layerB, layerA, []string{"enter a b", "enter b a"}},
+		{"only a watches", layerB, 0, []string{"enter a b"}},
+		{"only b watches", 0, layerA, []string{"enter b a"}},
+		{"neither watches", 0, 0, nil},
+		{"watching something else", layerC, layerC, nil},
+		{"watching everything", layerA | layerB | layerC, layerA | layerB | layerC, []string{"enter a b", "enter b a"}},
+	}
+
+	for _, tt := range tests {
+		var r recorder
+
+		w := NewWorld(64)
+		w.Add(newTestBody("a", 0, 0, 50, 50), layerA, tt.maskA, r.handlers())
+		w.Add(newTestBody("b", 10, 10, 60, 60), layerB, tt.maskB, r.handlers())
+
+		w.Step()
+
+		if got := r.take(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: events %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerDeactivates(t *testing.T) {
+	var r recorder
+
+	nest := newTestBody("nest", 0, 0, 100, 50)
+	egg := newTestBody("egg", 10, 10, 30, 30)
+	other := newTestBody("other", 40, 10, 60, 30)
+
+	h := r.handlers()
+	catch := h
+	catch.Enter = func(self, o Body) {
+		h.Enter(self, o)
+		self.(*testBody).active = false
+	}
+
+	w := NewWorld(64)
+	w.Add(nest, 1, 0, Handlers{})
+	w.Add(egg, 2, 1, catch)
+	w.Add(other, 2, 1, h)
+
+	w.Step()
+
+	want := []string{"enter egg nest", "exit egg nest", "enter other nest"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("first step: events %q, want %q", got, want)
+	}
+
+	w.Step()
+
+	want = []string{"stay other nest"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("second step: events %q, want %q", got, want)
+	}
+
+	// Back in play, it's a new contact
+	egg.active = true
+	w.Step()
+
+	want = []string{"enter egg nest", "exit egg nest", "stay other nest"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("third step: events %q, want %q", got, want)
+	}
+}
+
+func TestGridMatchesBruteForce(t *testing.T) {
+	hail, nest := newStorm(1000)
+
+	// Hail watches other hail here too, so there's plenty to find
+	w := NewWorld(DefaultCellSize)
+	w.Add(nest, layerNest, layerHail, Handlers{})
+	for _, h := range hail {
+		w.Add(h, layerHail, layerHail|layerNest, Handlers{})
+	}
+
+	all := append([]*box{nest}, hail...)
+
+	for step := 0; step < 20; step++ {
+		for _, h := range hail {
+			h.fall()
+		}
+
+		w.Step()
+
+		want := make(map[[2]*box]bool)
+		for i, a := range all {
+			for _, b := range all[i+1:] {
+				if a.TestCollision(&b.AABB) {
+					want[[2]*box{a, b}] = true
+					want[[2]*box{b, a}] = true
+				}
+			}
+		}
+
+		got := make(map[[2]*box]bool)
+		for _, c := range w.contacts {
+			a, b := c.a.Body.(*box), c.b.Body.(*box)
+			got[[2]*box{a, b}] = true
+			got[[2]*box{b, a}] = true
+		}
+
+		if len(w.contacts)*2 != len(got) {
+			t.Fatalf("step %d: %d contacts but only %d distinct pairs", step, len(w.contacts), len(got)/2)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("step %d: grid found %d contacts, brute force %d", step, len(got)/2, len(want)/2)
+		}
+	}
+}
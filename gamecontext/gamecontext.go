@@ -10,6 +10,26 @@ type gameContext struct {
 	PixelFormatEnum uint32
 
 	WindowWidth, WindowHeight int32
+
+	// LastGame is filled in by the play mode when a game ends, for whatever
+	// mode comes next
+	LastGame GameResult
+
+	// Seed for the gameplay random numbers. 0 picks a new one every game.
+	Seed int64
+
+	// Daily uses the daily challenge seed instead of Seed
+	Daily bool
+}
+
+// GameResult holds the outcome of a game
+type GameResult struct {
+	Score  int
+	Level  int
+	Caught int
+	Won    bool // cleared every level
+	Seed   int64
+	Daily  bool // was a daily challenge
 }
 
 // GContext holds the global game state
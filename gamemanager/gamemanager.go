@@ -24,6 +24,13 @@ type GameMode interface {
 	DidHide()
 }
 
+// DirtyRectRenderer is an optional GameMode interface for modes that only
+// redraw parts of the window. DirtyRects returns the regions changed by the
+// last Render.
+type DirtyRectRenderer interface {
+	DirtyRects() []sdl.Rect
+}
+
 // Event modes for eventMode
 const (
 	GameManagerEventDriven = iota
@@ -37,9 +44,10 @@ type GameManager struct {
 	nextModeID    int
 	modeMap       map[int]GameMode
 
-	FrameDelay   uint32 // ms
-	EventTimeout int
-	eventMode    int
+	FrameDelay     uint32 // ms
+	EventTimeout   int
+	eventMode      int
+	frameRequested bool
 
 	prevFrameTime uint32
 }
@@ -94,6 +102,17 @@ func (g *GameManager) Render(surface *sdl.Surface) {
 	g.modeMap[g.currentModeID].Render(surface)
 }
 
+// DirtyRects returns the regions of the window the current GameMode changed
+// in its last Render. The bool is false if the mode doesn't keep track, in
+// which case the whole window should be updated.
+func (g *GameManager) DirtyRects() ([]sdl.Rect, bool) {
+	if dr, ok := g.modeMap[g.currentModeID].(DirtyRectRenderer); ok {
+		return dr.DirtyRects(), true
+	}
+
+	return nil, false
+}
+
 // DelayToNextFrame waits until it's time to do the next event/render loop
 func (g *GameManager) DelayToNextFrame() {
 	curTime := sdl.GetTicks()
@@ -129,10 +148,23 @@ func (g *GameManager) SetEventMode(eventMode int) {
 	sdl.PushEvent(&sdl.UserEvent{Type: 0})
 }
 
+// RequestFrame asks for another frame soon, even in the event-driven mode, so
+// animations keep moving without any events coming in. It only holds for the
+// next frame, so keep calling it for as long as the animation runs.
+func (g *GameManager) RequestFrame() {
+	g.frameRequested = true
+}
+
 // GetNextEvent returns the next sdl.Event depending on the eventMode
 func (g *GameManager) GetNextEvent() sdl.Event {
+	requested := g.frameRequested
+	g.frameRequested = false
+
 	switch g.eventMode {
 	case GameManagerEventDriven:
+		if requested {
+			return sdl.WaitEventTimeout(int(g.FrameDelay))
+		}
 		return sdl.WaitEvent()
 	case GameManagerEventTimeoutDriven:
 		return sdl.WaitEventTimeout(g.EventTimeout)
@@ -5,4 +5,7 @@ package gamemanager
 const (
 	GameModeIntro = iota
 	GameModePlay
+	GameModeGameOver
+	GameModeNameEntry
+	GameModeHighScores
 )
@@ -0,0 +1,214 @@
+// Package gameoverstate shows the results of the last game and lets the player
+// try again or go back to the main menu.
+package gameoverstate
+
+import (
+	"fmt"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/menu"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const statsSpacing = 45 // px
+
+// GameOverState holds all information about the game over state
+type GameOverState struct {
+	assetManager            *assetmanager.AssetManager
+	rootEntity, statsEntity *scenegraph.Entity
+	bgColor                 uint32
+	fontNormalColor         sdl.Color
+	menu                    *menu.Menu
+}
+
+// Init initializes this gamestate
+func (gs *GameOverState) Init() {
+	// Create colors
+	gs.bgColor = sdl.MapRGB(gamecontext.GContext.PixelFormat, 120, 50, 40)
+	gs.fontNormalColor = sdl.Color{R: 255, G: 255, B: 255, A: 255}
+
+	gs.assetManager = assetmanager.New()
+
+	err := gs.assetManager.LoadJSON("gameoverassets.json")
+	if err != nil {
+		panic(fmt.Sprintf("gameoverassets.json: %v", err))
+	}
+
+	gs.buildScene()
+}
+
+func (gs *GameOverState) buildScene() {
+	am := gs.assetManager // asset manager
+
+	rootEntity := scenegraph.NewEntity(nil)
+	rootEntity.W = gamecontext.GContext.MainSurface.W
+	rootEntity.H = gamecontext.GContext.MainSurface.H
+
+	titleEntity := scenegraph.NewEntity(am.Surfaces["gameOverText"])
+
+	// Stats get filled in when the state is shown
+	gs.statsEntity = scenegraph.NewEntity(nil)
+	gs.statsEntity.W = rootEntity.W
+	gs.statsEntity.Y = 140
+
+	bindings := menu.Bindings{
+		Actions: map[string]func() bool{
+			"retry":    gs.setMode(gamemanager.GameModePlay),
+			"mainMenu": gs.setMode(gamemanager.GameModeIntro),
+		},
+	}
+
+	var err error
+
+	gs.menu, err = menu.LoadJSON(am, "gameoverassets.json", "gameOverMenu", bindings)
+	if err != nil {
+		panic(fmt.Sprintf("gameoverassets.json: %v", err))
+	}
+
+	rootEntity.AddChild(titleEntity, gs.statsEntity, gs.menu.RootEntity)
+
+	gs.rootEntity = rootEntity
+
+	// position title
+	scenegraph.CenterEntityInSurface(titleEntity, gamecontext.GContext.MainSurface)
+	titleEntity.Y = 40
+}
+
+// buildStats renders the results of the last game
+func (gs *GameOverState) buildStats() {
+	result := gamecontext.GContext.LastGame
+	font := gs.assetManager.Fonts["statsFont"]
+
+	lines := []string{
+		fmt.Sprintf("SCORE %d", result.Score),
+		fmt.Sprintf("LEVEL %d", result.Level),
+		fmt.Sprintf("EGGS CAUGHT %d", result.Caught),
+	}
+
+	if result.Won {
+		lines = append(lines, "ALL LEVELS CLEARED!")
+	}
+
+	if result.Daily {
+		lines = append(lines, fmt.Sprintf("DAILY SEED %d", result.Seed))
+	} else {
+		lines = append(lines, fmt.Sprintf("SEED %d", result.Seed))
+	}
+
+	// Throw away the old stats
+	for _, child := range gs.statsEntity.Children {
+		child.Surface.Free()
+	}
+	gs.statsEntity.Children = gs.statsEntity.Children[:0]
+
+	for i, line := range lines {
+		surface, err := util.RenderText(font, line, gs.fontNormalColor)
+		if err != nil {
+			panic(fmt.Sprintf("Game over stats render: %v", err))
+		}
+
+		entity := scenegraph.NewEntity(surface)
+		entity.Y = int32(i) * statsSpacing
+		scenegraph.CenterEntityInParent(entity, gs.statsEntity)
+
+		gs.statsEntity.AddChild(entity)
+	}
+}
+
+// setMode makes a menu action that switches to another mode
+func (gs *GameOverState) setMode(mode int) func() bool {
+	return func() bool {
+		gamemanager.GGameManager.SetMode(mode)
+		return false
+	}
+}
+
+// HandleEvent handles SDL events for the game over state
+func (gs *GameOverState) HandleEvent(event *sdl.Event) bool {
+
+	switch event := (*event).(type) {
+	case *sdl.KeyboardEvent:
+		// Only key presses, so the release of a key that brought us here
+		// doesn't count
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
+		switch event.Keysym.Sym {
+
+		case sdl.K_ESCAPE:
+			gamemanager.GGameManager.SetMode(gamemanager.GameModeIntro)
+
+		case sdl.K_DOWN:
+			gs.menu.SelectNext()
+
+		case sdl.K_UP:
+			gs.menu.SelectPrev()
+
+		case sdl.K_PAGEDOWN:
+			gs.menu.SelectPageDown()
+
+		case sdl.K_PAGEUP:
+			gs.menu.SelectPageUp()
+
+		case sdl.K_RETURN:
+			if gs.menu.Activate() {
+				return true // exit
+			}
+		}
+
+	case *sdl.MouseMotionEvent:
+		gs.menu.SelectByMouse(event.X, event.Y)
+
+	case *sdl.MouseWheelEvent:
+		gs.menu.Scroll(int(-event.Y))
+
+	case *sdl.MouseButtonEvent:
+		if event.Type == sdl.MOUSEBUTTONDOWN {
+			gs.menu.SelectByMouseClick(event.X, event.Y)
+
+			if gs.menu.ActivateClicked() {
+				return true // exit
+			}
+		}
+	}
+
+	return false
+}
+
+// Render renders the game over state
+func (gs *GameOverState) Render(mainWindowSurface *sdl.Surface) {
+	gs.menu.Update()
+
+	mainWindowSurface.FillRect(nil, gs.bgColor)
+	gs.rootEntity.Render(mainWindowSurface)
+}
+
+// WillShow is called just before this state begins
+func (gs *GameOverState) WillShow() {
+	gs.buildStats()
+	gs.menu.Reset()
+
+	// call this to move on to the next transition state
+	gamemanager.GGameManager.WillShowComplete()
+}
+
+// WillHide is called just before this state ends
+func (gs *GameOverState) WillHide() {
+}
+
+// DidShow is called just after this state begins
+func (gs *GameOverState) DidShow() {
+	gamemanager.GGameManager.SetEventMode(gamemanager.GameManagerEventDriven)
+
+	// Make sure the pointer is free to use the menu
+	util.SetMouseCapture(gamecontext.GContext.MainWindow, util.MouseCaptureNone)
+}
+
+// DidHide is called just after this state ends
+func (gs *GameOverState) DidHide() {
+}
@@ -0,0 +1,207 @@
+// Package highscore keeps the high score table on disk between runs.
+package highscore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// SchemaVersion is the version of the file format written by Save
+	SchemaVersion = 1
+
+	// MaxEntries is how many scores the table holds
+	MaxEntries = 10
+
+	// MaxNameLength is the longest name, in characters, the table keeps
+	MaxNameLength = 12
+
+	fileName = "highscores.json"
+	appDir   = "eggdrop"
+)
+
+// Entry is one line in the high score table
+type Entry struct {
+	Name  string
+	Score int
+	Level int
+	Seed  int64
+	Daily bool
+	Date  time.Time
+}
+
+// Table is the high score list, best first
+type Table struct {
+	Version int
+	Entries []Entry
+}
+
+// DataDir returns the directory where the high scores live. It follows the
+// XDG base directory spec, falling back to ~/.local/share.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, appDir), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", appDir), nil
+}
+
+// DefaultPath returns the full path of the high score file
+func DefaultPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the table from a file. A missing file is an empty table, not an
+// error.
+func Load(path string) (*Table, error) {
+	t := &Table{Version: SchemaVersion}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if t.Version > SchemaVersion {
+		return nil, fmt.Errorf("%s: schema version %d is newer than %d", path, t.Version, SchemaVersion)
+	}
+
+	// Older versions would be upgraded here
+	t.Version = SchemaVersion
+
+	t.sort()
+
+	return t, nil
+}
+
+// LoadDefault reads the table from the default path
+func LoadDefault() (*Table, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(path)
+}
+
+// Save writes the table to a file. It writes a temporary file first and then
+// renames it over the old one, so a crash never leaves a half-written table.
+func (t *Table) Save(path string) error {
+	data, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, fileName+".tmp")
+	if err != nil {
+		return err
+	}
+
+	// Clean up if anything goes wrong before the rename. After the rename,
+	// this fails harmlessly.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// SaveDefault writes the table to the default path
+func (t *Table) SaveDefault() error {
+	path, err := DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	return t.Save(path)
+}
+
+// sort orders the entries best first. Ties go to whoever got there first.
+func (t *Table) sort() {
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		return t.Entries[i].Score > t.Entries[j].Score
+	})
+
+	if len(t.Entries) > MaxEntries {
+		t.Entries = t.Entries[:MaxEntries]
+	}
+}
+
+// Qualifies reports whether a score is good enough to make the table
+func (t *Table) Qualifies(score int) bool {
+	if score <= 0 {
+		return false
+	}
+
+	if len(t.Entries) < MaxEntries {
+		return true
+	}
+
+	return score > t.Entries[len(t.Entries)-1].Score
+}
+
+// Add puts an entry in the table and returns its rank, starting from 0, or -1
+// if it didn't make the cut
+func (t *Table) Add(e Entry) int {
+	if !t.Qualifies(e.Score) {
+		return -1
+	}
+
+	if len([]rune(e.Name)) > MaxNameLength {
+		e.Name = string([]rune(e.Name)[:MaxNameLength])
+	}
+
+	// Goes after everyone with the same score or better
+	rank := sort.Search(len(t.Entries), func(i int) bool {
+		return t.Entries[i].Score < e.Score
+	})
+
+	t.Entries = append(t.Entries, Entry{})
+	copy(t.Entries[rank+1:], t.Entries[rank:])
+	t.Entries[rank] = e
+
+	if len(t.Entries) > MaxEntries {
+		t.Entries = t.Entries[:MaxEntries]
+	}
+
+	return rank
+}
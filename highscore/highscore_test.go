@@ -0,0 +1,185 @@
+package highscore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tableOf makes a table with entries of the given scores, in order
+func tableOf(scores ...int) *Table {
+	t := &Table{Version: SchemaVersion}
+
+	for i, s := range scores {
+		t.Entries = append(t.Entries, Entry{Name: fmt.Sprint("P", i), Score: s})
+	}
+
+	return t
+}
+
+// fullTable makes a full table scoring 100, 90, ... 10
+func fullTable() *Table {
+	var scores []int
+
+	for i := 0; i < MaxEntries; i++ {
+		scores = append(scores, (MaxEntries-i)*10)
+	}
+
+	return tableOf(scores...)
+}
+
+// scores returns the scores in a table, in order
+func scores(t *Table) []int {
+	var s []int
+
+	for _, e := range t.Entries {
+		s = append(s, e.Score)
+	}
+
+	return s
+}
+
+func TestQualifies(t *testing.T) {
+	tests := []struct {
+		name  string
+		table *Table
+		score int
+		want  bool
+	}{
+		{"empty table", tableOf(), 1, true},
+		{"empty table, zero score", tableOf(), 0, false},
+		{"empty table, negative score", tableOf(), -5, false},
+		{"room left", tableOf(100, 50), 1, true},
+		{"full table, better than lowest", fullTable(), 11, true},
+		{"full table, tied with lowest", fullTable(), 10, false},
+		{"full table, worse than lowest", fullTable(), 9, false},
+		{"full table, best", fullTable(), 1000, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.table.Qualifies(tt.score); got != tt.want {
+			t.Errorf("%s: Qualifies(%d) = %v, want %v", tt.name, tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name       string
+		table      *Table
+		score      int
+		wantRank   int
+		wantScores []int
+	}{
+		{"empty table", tableOf(), 5, 0, []int{5}},
+		{"top", tableOf(30, 20), 40, 0, []int{40, 30, 20}},
+		{"middle", tableOf(30, 20), 25, 1, []int{30, 25, 20}},
+		{"bottom", tableOf(30, 20), 10, 2, []int{30, 20, 10}},
+		{"tie goes after", tableOf(30, 20), 20, 2, []int{30, 20, 20}},
+		{"zero doesn't qualify", tableOf(30), 0, -1, []int{30}},
+		{"full, truncated", fullTable(), 55, 5, []int{100, 90, 80, 70, 60, 55, 50, 40, 30, 20}},
+		{"full, tied with lowest", fullTable(), 10, -1, []int{100, 90, 80, 70, 60, 50, 40, 30, 20, 10}},
+	}
+
+	for _, tt := range tests {
+		rank := tt.table.Add(Entry{Name: "NEW", Score: tt.score})
+
+		if rank != tt.wantRank {
+			t.Errorf("%s: Add(%d) rank = %d, want %d", tt.name, tt.score, rank, tt.wantRank)
+		}
+
+		if got := scores(tt.table); !reflect.DeepEqual(got, tt.wantScores) {
+			t.Errorf("%s: Add(%d) scores = %v, want %v", tt.name, tt.score, got, tt.wantScores)
+		}
+
+		if rank >= 0 && tt.table.Entries[rank].Name != "NEW" {
+			t.Errorf("%s: entry at rank %d is %q, want NEW", tt.name, rank, tt.table.Entries[rank].Name)
+		}
+	}
+}
+
+func TestAddTruncatesName(t *testing.T) {
+	table := tableOf()
+
+	table.Add(Entry{Name: "ÉÉÉÉÉÉÉÉÉÉÉÉÉÉÉ", Score: 1})
+
+	if got := []rune(table.Entries[0].Name); len(got) != MaxNameLength {
+		t.Errorf("name is %d runes, want %d", len(got), MaxNameLength)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", fileName)
+
+	table := tableOf(300, 200, 100)
+	table.Entries[1].Seed = 12345
+	table.Entries[1].Daily = true
+	table.Entries[1].Level = 7
+	table.Entries[1].Date = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if err := table.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded, table) {
+		t.Errorf("Load = %+v, want %+v", loaded, table)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	table, err := Load(filepath.Join(t.TempDir(), fileName))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if table.Version != SchemaVersion || len(table.Entries) != 0 {
+		t.Errorf("Load = %+v, want an empty table", table)
+	}
+}
+
+func TestLoadVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		wantErr bool
+	}{
+		{"older", SchemaVersion - 1, false},
+		{"current", SchemaVersion, false},
+		{"newer", SchemaVersion + 1, true},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), fileName)
+		data := fmt.Sprintf(`{"Version": %d, "Entries": [{"Name": "A", "Score": 5}]}`, tt.version)
+
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		table, err := Load(path)
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Load succeeded, want a schema version error", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Load: %v", tt.name, err)
+			continue
+		}
+
+		if table.Version != SchemaVersion {
+			t.Errorf("%s: Version = %d, want %d", tt.name, table.Version, SchemaVersion)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package highscorestate shows the high score table.
+package highscorestate
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/highscore"
+	"github.com/beejjorgensen/eggdrop/menu"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	listY       = 130 // px
+	listSpacing = 32  // px
+	listRows    = 8   // scores shown at once; the rest scroll
+
+	// The font is monospaced, so columns line up with plain formatting
+	listFormat = "%2s %-12s %7s %3s  %-10s %10s"
+)
+
+// HighScoreState holds all information about the high score state
+type HighScoreState struct {
+	assetManager                  *assetmanager.AssetManager
+	rootEntity, listEntity        *scenegraph.Entity
+	bgColor                       uint32
+	fontNormalColor, headingColor sdl.Color
+	list                          *menu.Menu
+}
+
+// Init initializes this gamestate
+func (hs *HighScoreState) Init() {
+	// Create colors
+	hs.bgColor = sdl.MapRGB(gamecontext.GContext.PixelFormat, 40, 60, 120)
+	hs.fontNormalColor = sdl.Color{R: 255, G: 255, B: 255, A: 255}
+	hs.headingColor = sdl.Color{R: 255, G: 255, B: 0, A: 255}
+
+	hs.assetManager = assetmanager.New()
+
+	err := hs.assetManager.LoadJSON("highscoreassets.json")
+	if err != nil {
+		panic(fmt.Sprintf("highscoreassets.json: %v", err))
+	}
+
+	hs.buildScene()
+}
+
+func (hs *HighScoreState) buildScene() {
+	am := hs.assetManager // asset manager
+
+	rootEntity := scenegraph.NewEntity(nil)
+	rootEntity.W = gamecontext.GContext.MainSurface.W
+	rootEntity.H = gamecontext.GContext.MainSurface.H
+
+	titleEntity := scenegraph.NewEntity(am.Surfaces["highScoresText"])
+	helpEntity := scenegraph.NewEntity(am.Surfaces["helpText"])
+
+	// The list gets filled in when the state is shown
+	hs.listEntity = scenegraph.NewEntity(nil)
+	hs.listEntity.W = rootEntity.W
+	hs.listEntity.Y = listY
+
+	rootEntity.AddChild(titleEntity, hs.listEntity, helpEntity)
+
+	hs.rootEntity = rootEntity
+
+	scenegraph.CenterEntityInParent(titleEntity, rootEntity)
+	titleEntity.Y = 40
+
+	scenegraph.CenterEntityInParent(helpEntity, rootEntity)
+	helpEntity.Y = 550
+}
+
+// buildList renders the current high score table
+func (hs *HighScoreState) buildList() {
+	font := hs.assetManager.Fonts["listFont"]
+
+	table, err := highscore.LoadDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "high scores: %v\n", err)
+		table = &highscore.Table{}
+	}
+
+	heading := fmt.Sprintf(listFormat, "", "NAME", "SCORE", "LVL", "DATE", "SEED")
+
+	var items []menu.Item
+
+	for i, e := range table.Entries {
+		seed := fmt.Sprint(e.Seed)
+		if e.Daily {
+			seed = "D" + seed
+		}
+
+		text := fmt.Sprintf(listFormat,
+			fmt.Sprint(i+1), e.Name, fmt.Sprint(e.Score), fmt.Sprint(e.Level),
+			e.Date.Local().Format("2006-01-02"), seed)
+
+		items = append(items, hs.listItem(text))
+	}
+
+	if len(table.Entries) == 0 {
+		items = append(items, hs.listItem("No scores yet!"))
+	}
+
+	// Throw away the old list
+	if hs.list != nil {
+		hs.list.Free()
+	}
+	for _, child := range hs.listEntity.Children {
+		if child.Surface != nil {
+			child.Surface.Free()
+		}
+	}
+	hs.listEntity.Children = hs.listEntity.Children[:0]
+
+	// The heading stays put while the scores scroll under it
+	surface, err := util.RenderText(font, heading, hs.headingColor)
+	if err != nil {
+		panic(fmt.Sprintf("High score render: %v", err))
+	}
+
+	headingEntity := scenegraph.NewEntity(surface)
+	scenegraph.CenterEntityInParent(headingEntity, hs.listEntity)
+
+	hs.list, err = menu.New(hs.assetManager, "highScoreList", items, listSpacing, menu.MenuJustifyCenter)
+	if err != nil {
+		panic(fmt.Sprintf("High score list: %v", err))
+	}
+
+	hs.list.SetViewportHeight(listRows * listSpacing)
+
+	// Leave room for the scroll up indicator under the heading
+	hs.list.RootEntity.Y = 2 * listSpacing
+	scenegraph.CenterEntityInParent(hs.list.RootEntity, hs.listEntity)
+
+	hs.listEntity.AddChild(headingEntity, hs.list.RootEntity)
+}
+
+// listItem makes a line of the score list
+func (hs *HighScoreState) listItem(text string) menu.Item {
+	return menu.Item{
+		AssetFontID: "listFont",
+		Text:        text,
+		Color:       hs.fontNormalColor,
+		HiColor:     hs.headingColor,
+	}
+}
+
+// HandleEvent handles SDL events for the high score state
+func (hs *HighScoreState) HandleEvent(event *sdl.Event) bool {
+
+	switch event := (*event).(type) {
+	case *sdl.KeyboardEvent:
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
+		switch event.Keysym.Sym {
+		case sdl.K_ESCAPE, sdl.K_RETURN:
+			gamemanager.GGameManager.SetMode(gamemanager.GameModeIntro)
+
+		case sdl.K_DOWN:
+			hs.list.SelectNext()
+
+		case sdl.K_UP:
+			hs.list.SelectPrev()
+
+		case sdl.K_PAGEDOWN:
+			hs.list.SelectPageDown()
+
+		case sdl.K_PAGEUP:
+			hs.list.SelectPageUp()
+		}
+
+	case *sdl.MouseMotionEvent:
+		hs.list.SelectByMouse(event.X, event.Y)
+
+	case *sdl.MouseWheelEvent:
+		hs.list.Scroll(int(-event.Y))
+
+	case *sdl.MouseButtonEvent:
+		if event.Type == sdl.MOUSEBUTTONDOWN {
+			gamemanager.GGameManager.SetMode(gamemanager.GameModeIntro)
+		}
+	}
+
+	return false
+}
+
+// Render renders the high score state
+func (hs *HighScoreState) Render(mainWindowSurface *sdl.Surface) {
+	hs.list.Update()
+
+	mainWindowSurface.FillRect(nil, hs.bgColor)
+	hs.rootEntity.Render(mainWindowSurface)
+}
+
+// WillShow is called just before this state begins
+func (hs *HighScoreState) WillShow() {
+	hs.buildList()
+
+	// call this to move on to the next transition state
+	gamemanager.GGameManager.WillShowComplete()
+}
+
+// WillHide is called just before this state ends
+func (hs *HighScoreState) WillHide() {
+}
+
+// DidShow is called just after this state begins
+func (hs *HighScoreState) DidShow() {
+	gamemanager.GGameManager.SetEventMode(gamemanager.GameManagerEventDriven)
+}
+
+// DidHide is called just after this state ends
+func (hs *HighScoreState) DidHide() {
+}
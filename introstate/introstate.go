@@ -4,29 +4,35 @@ package introstate
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/beejjorgensen/eggdrop/assetmanager"
 	"github.com/beejjorgensen/eggdrop/gamecontext"
 	"github.com/beejjorgensen/eggdrop/gamemanager"
 	"github.com/beejjorgensen/eggdrop/menu"
 	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// Seeds are entered with at most this many digits
+const seedMaxDigits = 9
+
 // IntroState holds all information about the intro state
 type IntroState struct {
-	assetManager                        *assetmanager.AssetManager
-	rootEntity                          *scenegraph.Entity
-	bgColor                             uint32
-	fontNormalColor, fontHighlightColor sdl.Color
-	menu                                *menu.Menu
+	assetManager           *assetmanager.AssetManager
+	rootEntity, seedEntity *scenegraph.Entity
+	bgColor                uint32
+	fontHighlightColor     sdl.Color
+	menu                   *menu.Menu
+	seedField              *menu.TextField
+	enteringSeed           bool
 }
 
 // Init initializes this gamestate
 func (is *IntroState) Init() {
 	// Create colors
 	is.bgColor = sdl.MapRGB(gamecontext.GContext.PixelFormat, 60, 160, 60)
-	is.fontNormalColor = sdl.Color{R: 255, G: 255, B: 255, A: 255}
 	is.fontHighlightColor = sdl.Color{R: 255, G: 255, B: 0, A: 255}
 
 	is.assetManager = assetmanager.New()
@@ -48,20 +54,26 @@ func (is *IntroState) buildScene() {
 
 	titleEntity := scenegraph.NewEntity(am.Surfaces["titleText"])
 
-	mColor := is.fontNormalColor
-	mHiColor := is.fontHighlightColor
-
-	menuItems := []menu.Item{
-		{AssetFontID: "menuFont", Text: "Play!", Color: mColor, HiColor: mHiColor},
-		{AssetFontID: "menuFont", Text: "Quit", Color: mColor, HiColor: mHiColor},
+	bindings := menu.Bindings{
+		Actions: map[string]func() bool{
+			"play":       func() bool { return is.play(false) },
+			"daily":      func() bool { return is.play(true) },
+			"playSeed":   is.enterSeed,
+			"highScores": is.showHighScores,
+			"quit":       func() bool { return true },
+		},
 	}
 
-	is.menu = menu.New(am, "introMenu", menuItems, 60, menu.MenuJustifyCenter)
+	var err error
 
-	scenegraph.CenterEntityInParent(is.menu.RootEntity, rootEntity)
-	is.menu.RootEntity.Y = 200
+	is.menu, err = menu.LoadJSON(am, "introassets.json", "introMenu", bindings)
+	if err != nil {
+		panic(fmt.Sprintf("introassets.json: %v", err))
+	}
 
-	rootEntity.AddChild(titleEntity, is.menu.RootEntity)
+	is.buildSeedEntry(rootEntity)
+
+	rootEntity.AddChild(titleEntity, is.menu.RootEntity, is.seedEntity)
 
 	is.rootEntity = rootEntity
 
@@ -71,28 +83,144 @@ func (is *IntroState) buildScene() {
 
 }
 
-// handleMenuItem does the right thing with a selected menu item
-func (is *IntroState) handleMenuItem(i int) bool {
-	switch i {
-	case 0: // Play!
-		gamemanager.GGameManager.SetMode(gamemanager.GameModePlay)
-	case 1: // Quit
-		return true // exit game
+// buildSeedEntry constructs the seed entry prompt, which takes the place of
+// the menu while it's up
+func (is *IntroState) buildSeedEntry(rootEntity *scenegraph.Entity) {
+	am := is.assetManager
+
+	is.seedEntity = scenegraph.NewEntity(nil)
+	is.seedEntity.W = rootEntity.W
+	is.seedEntity.H = rootEntity.H
+	is.seedEntity.Visible = false
+
+	promptEntity := scenegraph.NewEntity(am.Surfaces["seedPromptText"])
+	helpEntity := scenegraph.NewEntity(am.Surfaces["seedHelpText"])
+
+	is.seedField = menu.NewTextField(am, "menuFont", is.fontHighlightColor, is.fontHighlightColor, seedMaxDigits, menu.TextFieldDigits, menu.MenuJustifyCenter)
+
+	is.seedEntity.AddChild(promptEntity, is.seedField.RootEntity, helpEntity)
+
+	scenegraph.CenterEntityInParent(promptEntity, is.seedEntity)
+	promptEntity.Y = 220
+
+	scenegraph.CenterEntityInParent(is.seedField.RootEntity, is.seedEntity)
+	is.seedField.RootEntity.Y = 300
+
+	scenegraph.CenterEntityInParent(helpEntity, is.seedEntity)
+	helpEntity.Y = 500
+}
+
+// showSeedEntry switches between the menu and the seed entry prompt
+func (is *IntroState) showSeedEntry(show bool) {
+	gm := gamemanager.GGameManager
+
+	is.enteringSeed = show
+	is.seedEntity.Visible = show
+	is.menu.RootEntity.Visible = !show
+
+	if show {
+		seed := ""
+		if gamecontext.GContext.Seed != 0 {
+			seed = strconv.FormatInt(gamecontext.GContext.Seed, 10)
+		}
+		is.seedField.SetText(seed)
+		is.seedField.Start()
+
+		// Wake up now and then to blink the caret
+		gm.SetEventMode(gamemanager.GameManagerEventTimeoutDriven)
+	} else {
+		is.seedField.Stop()
+		gm.SetEventMode(gamemanager.GameManagerEventDriven)
+	}
+}
+
+// playSeed starts a game with the entered seed. A blank seed goes back to
+// picking a new one every game.
+func (is *IntroState) playSeed() {
+	gc := gamecontext.GContext
+
+	// Only digits get in, and not too many, so this can only fail if blank
+	seed, err := strconv.ParseInt(is.seedField.GetText(), 10, 64)
+	if err != nil {
+		seed = 0
+	}
+
+	gc.Seed = seed
+	gc.Daily = false
+
+	gamemanager.GGameManager.SetMode(gamemanager.GameModePlay)
+}
+
+// handleEventSeed handles events while the seed is being entered
+func (is *IntroState) handleEventSeed(event *sdl.Event) bool {
+	if is.seedField.HandleEvent(event) {
+		return false
+	}
+
+	switch event := (*event).(type) {
+	case *sdl.KeyboardEvent:
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
+		switch event.Keysym.Sym {
+		case sdl.K_ESCAPE:
+			is.showSeedEntry(false)
+
+		case sdl.K_RETURN:
+			is.playSeed()
+		}
+
+	case *sdl.ControllerButtonEvent:
+		if event.Type == sdl.CONTROLLERBUTTONDOWN && event.Button == sdl.CONTROLLER_BUTTON_START {
+			is.playSeed()
+		}
 	}
 
 	return false
 }
 
+// play starts a regular game or the daily challenge
+func (is *IntroState) play(daily bool) bool {
+	gamecontext.GContext.Daily = daily
+	gamemanager.GGameManager.SetMode(gamemanager.GameModePlay)
+
+	return false
+}
+
+// enterSeed brings up the seed entry prompt
+func (is *IntroState) enterSeed() bool {
+	is.showSeedEntry(true)
+
+	return false
+}
+
+// showHighScores switches to the high score table
+func (is *IntroState) showHighScores() bool {
+	gamemanager.GGameManager.SetMode(gamemanager.GameModeHighScores)
+
+	return false
+}
+
 // HandleEvent handles SDL events for the intro state
 func (is *IntroState) HandleEvent(event *sdl.Event) bool {
+	if is.enteringSeed {
+		return is.handleEventSeed(event)
+	}
 
 	switch event := (*event).(type) {
 	case *sdl.KeyboardEvent:
 		//fmt.Printf("Key: %#v\n", event)
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
 		switch event.Keysym.Sym {
 
 		case sdl.K_ESCAPE:
-			return true // exit game
+			if !is.menu.Back() {
+				return true // exit game
+			}
 
 		case sdl.K_DOWN:
 			is.menu.SelectNext()
@@ -100,24 +228,36 @@ func (is *IntroState) HandleEvent(event *sdl.Event) bool {
 		case sdl.K_UP:
 			is.menu.SelectPrev()
 
+		case sdl.K_PAGEDOWN:
+			is.menu.SelectPageDown()
+
+		case sdl.K_PAGEUP:
+			is.menu.SelectPageUp()
+
+		case sdl.K_LEFT:
+			is.menu.SelectLeft()
+
+		case sdl.K_RIGHT:
+			is.menu.SelectRight()
+
 		case sdl.K_RETURN:
-			if is.handleMenuItem(is.menu.GetSelected()) {
+			if is.menu.Activate() {
 				return true // exit
 			}
 		}
 
 	case *sdl.MouseMotionEvent:
-		is.menu.SelectByMouseY(event.Y)
+		is.menu.SelectByMouse(event.X, event.Y)
+
+	case *sdl.MouseWheelEvent:
+		is.menu.Scroll(int(-event.Y))
 
 	case *sdl.MouseButtonEvent:
 		if event.Type == sdl.MOUSEBUTTONDOWN {
-			is.menu.SelectByMouseClickY(event.Y)
+			is.menu.SelectByMouseClick(event.X, event.Y)
 
-			clicked := is.menu.GetClicked()
-			if clicked >= 0 {
-				if is.handleMenuItem(clicked) {
-					return true // exit
-				}
+			if is.menu.ActivateClicked() {
+				return true // exit
 			}
 		}
 	}
@@ -129,23 +269,34 @@ func (is *IntroState) HandleEvent(event *sdl.Event) bool {
 func (is *IntroState) Render(mainWindowSurface *sdl.Surface) {
 	rootEntity := is.rootEntity
 
+	is.seedField.Update()
+	is.menu.Update()
+
 	mainWindowSurface.FillRect(nil, is.bgColor)
 	rootEntity.Render(mainWindowSurface)
 }
 
 // WillShow is called just before this state begins
 func (is *IntroState) WillShow() {
+	is.menu.Show()
+
 	// call this to move on to the next transition state
 	gamemanager.GGameManager.WillShowComplete()
 }
 
 // WillHide is called just before this state ends
 func (is *IntroState) WillHide() {
+	if is.enteringSeed {
+		is.showSeedEntry(false)
+	}
 }
 
 // DidShow is called just after this statebegins
 func (is *IntroState) DidShow() {
 	gamemanager.GGameManager.SetEventMode(gamemanager.GameManagerEventDriven)
+
+	// Make sure the pointer is free to use the menu
+	util.SetMouseCapture(gamecontext.GContext.MainWindow, util.MouseCaptureNone)
 }
 
 // DidHide is called just after this state ends
@@ -2,13 +2,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"runtime"
 
 	"github.com/beejjorgensen/eggdrop/gamecontext"
 	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/gameoverstate"
+	"github.com/beejjorgensen/eggdrop/highscorestate"
 	"github.com/beejjorgensen/eggdrop/introstate"
+	"github.com/beejjorgensen/eggdrop/nameentrystate"
 	"github.com/beejjorgensen/eggdrop/playstate"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/img"
@@ -35,6 +41,9 @@ func sdlInit() {
 	if err := ttf.Init(); err != nil {
 		panic(fmt.Sprintf("Error initializing ttf: %v\n", err))
 	}
+
+	// Controllers have to be opened to get button events from them
+	util.OpenControllers()
 }
 
 func createMainWindow() {
@@ -69,19 +78,30 @@ func createMainWindow() {
 }
 
 func main() {
-	sdlInit()
-
 	gm := gamemanager.GGameManager
 	gc := gamecontext.GContext
 
+	flag.Int64Var(&gc.Seed, "seed", 0, "random seed for gameplay, so a game can be replayed (0 for a new one each game)")
+	flag.BoolVar(&scenegraph.FullRedraw, "fullredraw", false, "redraw the whole window every frame instead of just what changed")
+	flag.BoolVar(&scenegraph.DebugDirtyRects, "debugdirty", false, "outline the regions redrawn each frame")
+	flag.Parse()
+
+	sdlInit()
+
 	createMainWindow()
 	defer gc.MainWindow.Destroy()
 
 	intro := &introstate.IntroState{}
 	play := &playstate.PlayState{}
+	gameOver := &gameoverstate.GameOverState{}
+	nameEntry := &nameentrystate.NameEntryState{}
+	highScores := &highscorestate.HighScoreState{}
 
 	gm.RegisterMode(gamemanager.GameModeIntro, intro)
 	gm.RegisterMode(gamemanager.GameModePlay, play)
+	gm.RegisterMode(gamemanager.GameModeGameOver, gameOver)
+	gm.RegisterMode(gamemanager.GameModeNameEntry, nameEntry)
+	gm.RegisterMode(gamemanager.GameModeHighScores, highScores)
 
 	done := false
 
@@ -105,11 +125,21 @@ func main() {
 				if event.Event == sdl.WINDOWEVENT_CLOSE {
 					done = done || true
 				}
+
+			case *sdl.ControllerDeviceEvent:
+				util.HandleControllerDeviceEvent(event)
 			}
 		}
 
 		gm.Render(mainWindowSurface)
-		mainWindow.UpdateSurface()
+
+		if rects, ok := gm.DirtyRects(); ok {
+			if len(rects) > 0 {
+				mainWindow.UpdateSurfaceRects(rects)
+			}
+		} else {
+			mainWindow.UpdateSurface()
+		}
 
 		gm.DelayToNextFrame()
 	}
@@ -0,0 +1,187 @@
+package menu
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	pulseShades = 6    // number of highlight shades to cycle through
+	pulseAmount = 0.6  // how far toward white the brightest shade goes
+	pulsePeriod = 1000 // ms for a full bright-and-back cycle
+
+	cursorText = ">"
+	cursorGap  = 12   // px between the cursor and the item
+	cursorEase = 18.0 // 1/s, how quickly the cursor closes in on its target
+
+	fadeDuration = 200 // ms for each item to fade in
+	fadeStagger  = 60  // ms between each item starting to fade in
+)
+
+// animation is the time-driven state of the menu effects
+type animation struct {
+	shownAt    uint32 // when the current page appeared
+	lastUpdate uint32
+
+	cursor           *scenegraph.Entity
+	cursorX, cursorY float64
+}
+
+// renderShades makes the highlight pulse surfaces for an item, from the
+// plain highlight color toward white
+func (m *Menu) renderShades(info *itemInfo, text string, hiColor sdl.Color) {
+	// Shade 0 is the highlight surface itself, freed along with the entity's
+	for i := 1; i < len(info.shades); i++ {
+		info.shades[i].Free()
+	}
+
+	info.shades = append(info.shades[:0], info.entityHi.Surface)
+
+	if info.Disabled {
+		return // no pulse
+	}
+
+	font := m.am.Fonts[info.AssetFontID]
+
+	for i := 1; i < pulseShades; i++ {
+		f := pulseAmount * float64(i) / float64(pulseShades-1)
+
+		shade := sdl.Color{
+			R: uint8(float64(hiColor.R) + (255-float64(hiColor.R))*f),
+			G: uint8(float64(hiColor.G) + (255-float64(hiColor.G))*f),
+			B: uint8(float64(hiColor.B) + (255-float64(hiColor.B))*f),
+			A: hiColor.A,
+		}
+
+		surface, err := util.RenderText(font, text, shade)
+		if err != nil {
+			panic(fmt.Sprintf("Menu render font: %v", err))
+		}
+
+		info.shades = append(info.shades, surface)
+	}
+}
+
+// buildCursor makes the cursor that slides to the selected item, in the
+// style of the first item
+func (m *Menu) buildCursor(item Item) {
+	surface, err := m.am.RenderText(m.id+"-cursor", item.AssetFontID, cursorText, item.HiColor)
+	if err != nil {
+		panic(fmt.Sprintf("Menu render font: %v", err))
+	}
+
+	m.SetCursor(surface)
+}
+
+// SetCursor replaces the cursor image
+func (m *Menu) SetCursor(surface *sdl.Surface) {
+	if m.anim.cursor == nil {
+		m.anim.cursor = scenegraph.NewEntity(nil)
+		m.RootEntity.AddChild(m.anim.cursor)
+	}
+
+	m.anim.cursor.Surface = surface
+	m.anim.cursor.W = surface.W
+	m.anim.cursor.H = surface.H
+}
+
+// cursorTarget returns where the cursor should end up, next to the selected
+// item
+func (m *Menu) cursorTarget() (float64, float64) {
+	p := m.current()
+	info := p.items[p.selected]
+	cursor := m.anim.cursor
+
+	x := info.entityHi.X - cursor.W - cursorGap
+	y := info.entityHi.Y - p.scroll + (info.entityHi.H-cursor.H)/2
+
+	return float64(x), float64(y)
+}
+
+// Show starts the appearing animation: the items fade in one after the other
+// and the cursor slides in from the left edge of the window
+func (m *Menu) Show() {
+	now := sdl.GetTicks()
+
+	// Whatever was under the mouse may have gone with the old page
+	m.mouse.Reset()
+
+	m.anim.shownAt = now
+	m.anim.lastUpdate = now
+
+	m.anim.cursorX = float64(-m.RootEntity.X - m.anim.cursor.W)
+	_, m.anim.cursorY = m.cursorTarget()
+
+	m.Update()
+}
+
+// pulseShade picks the highlight shade for a moment in time, going up and
+// back down again each period
+func pulseShade(now uint32) int {
+	phase := float64(now%pulsePeriod) / pulsePeriod
+	tri := 1 - math.Abs(2*phase-1) // 0 -> 1 -> 0
+
+	return int(tri*(pulseShades-1) + 0.5)
+}
+
+// fadeAlpha returns the alpha for an item some time after the page appeared
+func fadeAlpha(elapsed uint32, index int) uint8 {
+	start := uint32(index) * fadeStagger
+
+	switch {
+	case elapsed <= start:
+		return 0
+	case elapsed >= start+fadeDuration:
+		return 255
+	}
+
+	return uint8((elapsed - start) * 255 / fadeDuration)
+}
+
+// Update runs the menu animations. Call it every frame before rendering.
+// It asks the GameManager for frames, so it keeps going in the event-driven
+// mode, too.
+func (m *Menu) Update() {
+	now := sdl.GetTicks()
+	dt := float64(now-m.anim.lastUpdate) / 1000
+	m.anim.lastUpdate = now
+
+	p := m.current()
+	elapsed := now - m.anim.shownAt
+
+	// Fade in and pulse
+	for i, info := range p.items {
+		if i == p.selected && len(info.shades) > 1 {
+			info.entityHi.Surface = info.shades[pulseShade(now)]
+		} else {
+			info.entityHi.Surface = info.shades[0]
+		}
+
+		alpha := fadeAlpha(elapsed, i)
+
+		for _, e := range []*scenegraph.Entity{info.entity, info.entityHi} {
+			if old, err := e.Surface.GetAlphaMod(); err != nil || old != alpha {
+				e.Surface.SetAlphaMod(alpha)
+				e.MarkDirty()
+			}
+		}
+	}
+
+	// Slide the cursor
+	targetX, targetY := m.cursorTarget()
+	ease := math.Min(1, dt*cursorEase)
+
+	m.anim.cursorX += (targetX - m.anim.cursorX) * ease
+	m.anim.cursorY += (targetY - m.anim.cursorY) * ease
+
+	m.anim.cursor.X = int32(math.Round(m.anim.cursorX))
+	m.anim.cursor.Y = int32(math.Round(m.anim.cursorY))
+
+	// The pulse never stops, so there's always another frame wanted
+	gamemanager.GGameManager.RequestFrame()
+}
@@ -0,0 +1,234 @@
+package menu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Bindings connects the action names in a JSON menu to code. Plain items look
+// up their Action in Actions. Selectors and toggles look theirs up in Changes,
+// which are called with the new value.
+type Bindings struct {
+	Actions map[string]func() bool
+	Changes map[string]func(value int)
+}
+
+// jsonItem is an item in the "Menus" JSON. Font and colors default to the
+// menu's.
+type jsonItem struct {
+	ID           string `json:"Id"`
+	Text         string
+	Font         string
+	Rgba         []int
+	HiRgba       []int
+	DisabledRgba []int
+	Disabled     bool
+	Action       string
+	Values       []string
+	Toggle       bool
+	Value        int
+	Back         bool
+	Items        []jsonItem // submenu
+}
+
+// jsonMenu is a menu in the "Menus" JSON. X may be a number or "CENTER" to
+// center the menu in the window. ViewportH limits the height, scrolling the
+// rest.
+type jsonMenu struct {
+	ID           string `json:"Id"`
+	Font         string
+	Rgba         []int
+	HiRgba       []int
+	DisabledRgba []int
+	Justify      string
+	Spacing      int32
+	X            interface{}
+	Y            int32
+	ViewportH    int32
+	Items        []jsonItem
+}
+
+// jsonColor converts an [R,G,B,A] array, or uses the fallback if it's absent
+func jsonColor(rgba []int, fallback sdl.Color) (sdl.Color, error) {
+	if len(rgba) == 0 {
+		return fallback, nil
+	}
+
+	if len(rgba) != 4 {
+		return fallback, errors.New("Rgba needs to be in form [R,G,B,A], 0-255 for each element")
+	}
+
+	for _, c := range rgba {
+		if c < 0 || c > 255 {
+			return fallback, errors.New("Rgba needs to be in form [R,G,B,A], 0-255 for each element")
+		}
+	}
+
+	return sdl.Color{R: uint8(rgba[0]), G: uint8(rgba[1]), B: uint8(rgba[2]), A: uint8(rgba[3])}, nil
+}
+
+// items converts JSON items to menu Items, binding their actions
+func (jm *jsonMenu) items(am *assetmanager.AssetManager, jItems []jsonItem, color, hiColor, disabledColor sdl.Color, bindings Bindings) ([]Item, error) {
+	var items []Item
+
+	for _, ji := range jItems {
+		var err error
+
+		item := Item{
+			ID:          ji.ID,
+			AssetFontID: ji.Font,
+			Text:        ji.Text,
+			Disabled:    ji.Disabled,
+			Values:      ji.Values,
+			Toggle:      ji.Toggle,
+			Value:       ji.Value,
+			Back:        ji.Back,
+		}
+
+		if item.AssetFontID == "" {
+			item.AssetFontID = jm.Font
+		}
+		if _, ok := am.Fonts[item.AssetFontID]; !ok {
+			return nil, fmt.Errorf("%s: unknown Font %q", ji.Text, item.AssetFontID)
+		}
+
+		if item.Color, err = jsonColor(ji.Rgba, color); err != nil {
+			return nil, fmt.Errorf("%s: %v", ji.Text, err)
+		}
+		if item.HiColor, err = jsonColor(ji.HiRgba, hiColor); err != nil {
+			return nil, fmt.Errorf("%s: %v", ji.Text, err)
+		}
+		if item.DisabledColor, err = jsonColor(ji.DisabledRgba, disabledColor); err != nil {
+			return nil, fmt.Errorf("%s: %v", ji.Text, err)
+		}
+
+		if item.Value < 0 || (len(item.Values) > 0 && item.Value >= len(item.Values)) {
+			return nil, fmt.Errorf("%s: Value %d out of range", ji.Text, item.Value)
+		}
+
+		if ji.Action != "" {
+			if item.Toggle || len(item.Values) > 0 {
+				item.OnChange = bindings.Changes[ji.Action]
+				if item.OnChange == nil {
+					return nil, fmt.Errorf("%s: no change handler bound to %q", ji.Text, ji.Action)
+				}
+			} else {
+				item.Action = bindings.Actions[ji.Action]
+				if item.Action == nil {
+					return nil, fmt.Errorf("%s: no action bound to %q", ji.Text, ji.Action)
+				}
+			}
+		}
+
+		if len(ji.Items) > 0 {
+			if item.Submenu, err = jm.items(am, ji.Items, color, hiColor, disabledColor, bindings); err != nil {
+				return nil, err
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// LoadJSON builds a menu from the "Menus" section of a JSON file. The fonts
+// it refers to must already be loaded into the asset manager.
+func LoadJSON(am *assetmanager.AssetManager, jsonFile, id string, bindings Bindings) (*Menu, error) {
+	jsonStr, err := ioutil.ReadFile(assetmanager.AssetPath(jsonFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonRoot struct {
+		Menus []jsonMenu
+	}
+
+	if err = json.Unmarshal(jsonStr, &jsonRoot); err != nil {
+		return nil, err
+	}
+
+	var jm *jsonMenu
+
+	for i := range jsonRoot.Menus {
+		if jsonRoot.Menus[i].ID == id {
+			jm = &jsonRoot.Menus[i]
+			break
+		}
+	}
+
+	if jm == nil {
+		return nil, fmt.Errorf("no menu %q in Menus", id)
+	}
+
+	color, err := jsonColor(jm.Rgba, sdl.Color{R: 255, G: 255, B: 255, A: 255})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", id, err)
+	}
+	hiColor, err := jsonColor(jm.HiRgba, sdl.Color{R: 255, G: 255, B: 0, A: 255})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", id, err)
+	}
+	disabledColor, err := jsonColor(jm.DisabledRgba, defaultDisabledColor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", id, err)
+	}
+
+	var justification int
+
+	switch jm.Justify {
+	case "LEFT":
+		justification = MenuJustifyLeft
+	case "CENTER", "":
+		justification = MenuJustifyCenter
+	case "RIGHT":
+		justification = MenuJustifyRight
+	default:
+		return nil, fmt.Errorf("%s: unknown Justify %q", id, jm.Justify)
+	}
+
+	items, err := jm.items(am, jm.Items, color, hiColor, disabledColor, bindings)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", id, err)
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s: no Items", id)
+	}
+
+	if jm.Spacing <= 0 {
+		return nil, fmt.Errorf("%s: Spacing must be positive", id)
+	}
+
+	m, err := New(am, id, items, jm.Spacing, justification)
+	if err != nil {
+		return nil, err
+	}
+
+	if jm.ViewportH > 0 {
+		m.SetViewportHeight(jm.ViewportH)
+	}
+
+	switch x := jm.X.(type) {
+	case nil:
+	case float64:
+		m.RootEntity.X = int32(x)
+	case string:
+		if x != "CENTER" {
+			return nil, fmt.Errorf("%s: unknown X %q", id, x)
+		}
+		m.RootEntity.X = (gamecontext.GContext.MainSurface.W - m.RootEntity.W) / 2
+	default:
+		return nil, fmt.Errorf("%s: X must be a number or \"CENTER\"", id)
+	}
+
+	m.RootEntity.Y = jm.Y
+
+	return m, nil
+}
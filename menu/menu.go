@@ -1,6 +1,9 @@
 // Package menu renders and controls a text-based menu. There are helper
 // functions to control the position by keyboard or mouse, though the actual
 // event handling takes place outside this package.
+//
+// Items can carry an Action to run when chosen, be disabled, pick from a list
+// of values with left and right, toggle on and off, or open a submenu.
 package menu
 
 import (
@@ -13,18 +16,56 @@ import (
 
 // Menu holds information about on-screen menus
 type Menu struct {
-	items             []Item
-	selected, clicked int
-	spacing           int32 // px
-	justification     int
-	RootEntity        *scenegraph.Entity
+	am            *assetmanager.AssetManager
+	id            string
+	nextID        int
+	path          []*page // open submenus, top level first
+	clicked       int
+	spacing       int32 // px
+	justification int
+	contentH      int32 // px, height of the tallest page
+	viewportH     int32 // px, 0 for no limit
+
+	viewEntity           *scenegraph.Entity // clips the pages to the viewport
+	mouse                scenegraph.MouseTracker
+	upEntity, downEntity *scenegraph.Entity // scroll indicators
+	RootEntity           *scenegraph.Entity
+
+	anim animation
 }
 
 // Item describes an individual Menu line item
 type Item struct {
+	ID             string // optional, for finding the item later
 	AssetFontID    string
 	Text           string
 	Color, HiColor sdl.Color
+	DisabledColor  sdl.Color // defaults to gray
+
+	// Disabled items are grayed out and can't be selected
+	Disabled bool
+
+	// Action is called when the item is chosen. Returning true exits the
+	// game.
+	Action func() bool
+
+	// Values makes the item a selector, changed with left and right. Value
+	// is the index of the current one.
+	Values []string
+
+	// Toggle makes the item a checkbox. Value is 1 when checked.
+	Toggle bool
+
+	Value int
+
+	// OnChange is called with the new Value of a selector or toggle
+	OnChange func(value int)
+
+	// Submenu is opened when the item is chosen
+	Submenu []Item
+
+	// Back returns to the parent menu when chosen
+	Back bool
 }
 
 // Justification constants for Menu
@@ -34,168 +75,687 @@ const (
 	MenuJustifyRight
 )
 
-// New constructs the menu
-func New(am *assetmanager.AssetManager, id string, items []Item, spacing int32, justification int) *Menu {
+var defaultDisabledColor = sdl.Color{R: 128, G: 128, B: 128, A: 255}
+
+// itemInfo is an Item along with its on-screen parts
+type itemInfo struct {
+	Item
+	surfaceID        string
+	entity, entityHi *scenegraph.Entity
+	shades           []*sdl.Surface // highlight pulse, from HiColor up
+	submenu          *page
+}
+
+// page is one level of the menu
+type page struct {
+	items    []*itemInfo
+	selected int
+	scroll   int32 // px
+	entity   *scenegraph.Entity
+}
+
+// New constructs the menu. The scroll indicators and cursor are styled after
+// the first item, so there has to be at least one.
+func New(am *assetmanager.AssetManager, id string, items []Item, spacing int32, justification int) (*Menu, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("menu %s: no items", id)
+	}
+
 	// TODO: do these even need to be registered with any asset manager? just use util.RenderText?
-	menu := &Menu{items: items, spacing: spacing, justification: justification}
+	menu := &Menu{am: am, id: id, spacing: spacing, justification: justification}
 
-	menuEntity := scenegraph.NewEntity(nil)
+	menu.RootEntity = scenegraph.NewEntity(nil)
+	menu.viewEntity = scenegraph.NewEntity(nil)
+	menu.RootEntity.AddChild(menu.viewEntity)
 
-	maxH := int32(0)
-	maxW := int32(0)
+	top := menu.newPage(items)
+	top.entity.Visible = true
 
-	idNum := 0
+	menu.path = []*page{top}
 
-	for i, item := range menu.items {
-		var err error
-		var surface, surfaceHi *sdl.Surface
-		var entity, entityHi *scenegraph.Entity
+	// Every page is laid out to the size of the biggest so the menu can be
+	// positioned once
+	menu.layoutAll(top)
+	menu.buildScrollIndicators(items[0])
+	menu.buildCursor(items[0])
+	menu.updateVisibility()
+	menu.Show()
+
+	return menu, nil
+}
 
-		if surface, err = am.RenderText(fmt.Sprintf("%s-%d", id, idNum), item.AssetFontID, item.Text, item.Color); err != nil {
+// buildScrollIndicators makes the arrows that show there's more above or
+// below the viewport, in the style of the first item
+func (m *Menu) buildScrollIndicators(item Item) {
+	for _, e := range []struct {
+		entity **scenegraph.Entity
+		id     string
+		text   string
+	}{
+		{&m.upEntity, m.id + "-up", "^"},
+		{&m.downEntity, m.id + "-down", "v"},
+	} {
+		surface, err := m.am.RenderText(e.id, item.AssetFontID, e.text, item.Color)
+		if err != nil {
 			panic(fmt.Sprintf("Menu render font: %v", err))
 		}
 
-		idNum++
+		*e.entity = scenegraph.NewEntity(surface)
+		(*e.entity).Visible = false
+		scenegraph.CenterEntityInParent(*e.entity, m.RootEntity)
+
+		m.RootEntity.AddChild(*e.entity)
+	}
+}
 
-		if surfaceHi, err = am.RenderText(fmt.Sprintf("%s-%d", id, idNum), item.AssetFontID, item.Text, item.HiColor); err != nil {
-			panic(fmt.Sprintf("Intro render font: %v", err))
+// newPage builds a page and its submenus
+func (m *Menu) newPage(items []Item) *page {
+	p := &page{entity: scenegraph.NewEntity(nil)}
+	p.entity.Visible = false
+
+	m.viewEntity.AddChild(p.entity)
+
+	for i, item := range items {
+		info := &itemInfo{Item: item}
+
+		if info.DisabledColor == (sdl.Color{}) {
+			info.DisabledColor = defaultDisabledColor
 		}
 
-		idNum++
+		info.surfaceID = fmt.Sprintf("%s-%d", m.id, m.nextID)
+		m.nextID++
 
-		entity = scenegraph.NewEntity(surface)
-		entity.Visible = i > 0
-		entityHi = scenegraph.NewEntity(surfaceHi)
-		entityHi.Visible = i == 0
+		info.entity = scenegraph.NewEntity(nil)
+		info.entityHi = scenegraph.NewEntity(nil)
+		info.entity.Y = int32(i) * m.spacing
+		info.entityHi.Y = info.entity.Y
 
-		menuEntity.AddChild(entity, entityHi)
+		p.entity.AddChild(info.entity, info.entityHi)
 
-		if entity.W > maxW {
-			maxW = entity.W
+		index := i
+		for _, e := range []*scenegraph.Entity{info.entity, info.entityHi} {
+			e.OnMouseEnter = func(*scenegraph.Entity) { m.hoverItem(p, index) }
+			e.OnClick = func(*scenegraph.Entity) { m.clickItem(p, index) }
 		}
 
-		entity.Y = maxH
-		entityHi.Y = maxH
+		if len(item.Submenu) > 0 {
+			info.submenu = m.newPage(item.Submenu)
+		}
+
+		m.renderItem(info)
 
-		maxH += menu.spacing
+		p.items = append(p.items, info)
 	}
 
-	menuEntity.W = maxW
-	menuEntity.H = maxH
+	p.selected = p.firstEnabled()
 
-	// position everything now that we have sizes known
-	for i := range menu.items {
+	return p
+}
 
-		entity := menuEntity.GetChild(i * 2)
-		entityHi := menuEntity.GetChild(i*2 + 1)
+// label returns the text shown for an item with the given value
+func (info *itemInfo) label(value int) string {
+	switch {
+	case info.Toggle && value != 0:
+		return "[X] " + info.Text
+	case info.Toggle:
+		return "[ ] " + info.Text
+	case len(info.Values) > 0:
+		return fmt.Sprintf("%s: < %s >", info.Text, info.Values[value])
+	}
+
+	return info.Text
+}
+
+// maxWidth returns the widest the item can get, for any of its values
+func (m *Menu) maxWidth(info *itemInfo) int32 {
+	font := m.am.Fonts[info.AssetFontID]
+
+	values := 1
+	switch {
+	case info.Toggle:
+		values = 2
+	case len(info.Values) > 0:
+		values = len(info.Values)
+	}
+
+	maxW := 0
+
+	for v := 0; v < values; v++ {
+		w, _, err := font.SizeUTF8(info.label(v))
+		if err != nil {
+			panic(fmt.Sprintf("Menu size font: %v", err))
+		}
+		if w > maxW {
+			maxW = w
+		}
+	}
+
+	return int32(maxW)
+}
+
+// renderItem draws an item's normal and highlighted text
+func (m *Menu) renderItem(info *itemInfo) {
+	text := info.label(info.Value)
+
+	// Put back the plain highlight if it's mid-pulse
+	if len(info.shades) > 0 {
+		info.entityHi.Surface = info.shades[0]
+	}
+
+	color, hiColor := info.Color, info.HiColor
+	if info.Disabled {
+		color, hiColor = info.DisabledColor, info.DisabledColor
+	}
+
+	for _, e := range []struct {
+		entity *scenegraph.Entity
+		id     string
+		color  sdl.Color
+	}{
+		{info.entity, info.surfaceID, color},
+		{info.entityHi, info.surfaceID + "-hi", hiColor},
+	} {
+		if e.entity.Surface != nil {
+			e.entity.Surface.Free()
+		}
+
+		surface, err := m.am.RenderText(e.id, info.AssetFontID, text, e.color)
+		if err != nil {
+			panic(fmt.Sprintf("Menu render font: %v", err))
+		}
+
+		e.entity.Surface = surface
+		e.entity.W = surface.W
+		e.entity.H = surface.H
+		e.entity.MarkDirty()
+	}
 
-		switch menu.justification {
+	m.renderShades(info, text, hiColor)
+	m.justify(info)
+}
+
+// justify positions an item's text within the menu width
+func (m *Menu) justify(info *itemInfo) {
+	for _, entity := range []*scenegraph.Entity{info.entity, info.entityHi} {
+		switch m.justification {
 		case MenuJustifyLeft:
 			entity.X = 0
-			entityHi.X = 0
 		case MenuJustifyCenter:
-			scenegraph.CenterEntityInParent(entity, menuEntity)
-			scenegraph.CenterEntityInParent(entityHi, menuEntity)
+			entity.X = (m.RootEntity.W - entity.W) / 2
 		case MenuJustifyRight:
-			scenegraph.RightJustifyEntityInParent(entity, menuEntity)
-			scenegraph.RightJustifyEntityInParent(entityHi, menuEntity)
+			entity.X = m.RootEntity.W - entity.W
 		}
 	}
+}
 
-	menu.RootEntity = menuEntity
+// layoutAll sizes the menu to fit every page, then positions all the items
+func (m *Menu) layoutAll(top *page) {
+	var maxW, maxH int32
 
-	return menu
+	var measure func(p *page)
+	measure = func(p *page) {
+		if h := int32(len(p.items)) * m.spacing; h > maxH {
+			maxH = h
+		}
+
+		for _, info := range p.items {
+			if w := m.maxWidth(info); w > maxW {
+				maxW = w
+			}
+			if info.submenu != nil {
+				measure(info.submenu)
+			}
+		}
+	}
+
+	measure(top)
+
+	m.RootEntity.W = maxW
+	m.RootEntity.H = maxH
+	m.viewEntity.W = maxW
+	m.viewEntity.H = maxH
+	m.contentH = maxH
+
+	var position func(p *page)
+	position = func(p *page) {
+		p.entity.W = maxW
+		p.entity.H = maxH
+
+		for _, info := range p.items {
+			m.justify(info)
+			if info.submenu != nil {
+				position(info.submenu)
+			}
+		}
+	}
+
+	position(top)
+}
+
+// current returns the page being shown
+func (m *Menu) current() *page {
+	return m.path[len(m.path)-1]
+}
+
+// firstEnabled returns the index of the first item that can be selected
+func (p *page) firstEnabled() int {
+	for i, info := range p.items {
+		if !info.Disabled {
+			return i
+		}
+	}
+
+	return 0
 }
 
-// updateVisibility sets the visibility flags on the appropriate elements
+// updateVisibility sets the visibility flags on the appropriate elements, and
+// scrolls the selected item into view
 func (m *Menu) updateVisibility() {
-	root := m.RootEntity
+	p := m.current()
+
+	for i, info := range p.items {
+		info.entity.Visible = i != p.selected   // unselected
+		info.entityHi.Visible = i == p.selected // selected
+	}
 
-	for i := range m.items {
-		eIndex := i * 2
+	top := int32(p.selected) * m.spacing
+	viewH := m.viewEntity.H
 
-		if i == m.selected {
-			root.GetChild(eIndex).Visible = false    // unselected
-			root.GetChild(eIndex + 1).Visible = true // selected
-		} else {
-			root.GetChild(eIndex).Visible = true
-			root.GetChild(eIndex + 1).Visible = false
-		}
+	switch {
+	case top < p.scroll:
+		m.scrollTo(top)
+	case top+m.spacing > p.scroll+viewH:
+		m.scrollTo(top + m.spacing - viewH)
+	default:
+		m.scrollTo(p.scroll)
+	}
+}
+
+// SetViewportHeight limits how much of the menu is shown at once. The rest
+// scrolls into view as needed. 0 shows the whole thing.
+func (m *Menu) SetViewportHeight(h int32) {
+	m.viewportH = h
+
+	if h <= 0 || h >= m.contentH {
+		h = m.contentH
+	}
+
+	m.viewEntity.H = h
+	m.viewEntity.Clip = h < m.contentH
+	m.RootEntity.H = h
+
+	m.upEntity.Y = -m.upEntity.H
+	m.downEntity.Y = h
+
+	m.updateVisibility()
+}
+
+// scrollTo scrolls the current page, keeping it within bounds
+func (m *Menu) scrollTo(offset int32) {
+	p := m.current()
+
+	maxScroll := int32(len(p.items))*m.spacing - m.viewEntity.H
+	if offset > maxScroll {
+		offset = maxScroll
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	p.scroll = offset
+	p.entity.Y = -offset
+
+	m.upEntity.Visible = offset > 0
+	m.downEntity.Visible = offset < maxScroll
+}
+
+// Scroll moves the view by some number of items, as for a mouse wheel,
+// without changing the selection
+func (m *Menu) Scroll(items int) {
+	m.scrollTo(m.current().scroll + int32(items)*m.spacing)
+}
+
+// pageSize returns how many items fit in the viewport
+func (m *Menu) pageSize() int {
+	n := int(m.viewEntity.H / m.spacing)
+	if n < 1 {
+		n = 1
+	}
+
+	return n
+}
+
+// jump moves the selection by n items, stopping at the ends instead of
+// wrapping, and skipping over disabled items
+func (m *Menu) jump(n int) {
+	p := m.current()
+
+	target := p.selected + n
+	if target < 0 {
+		target = 0
+	}
+	if target >= len(p.items) {
+		target = len(p.items) - 1
+	}
 
+	// Back up toward where we started until we find something selectable
+	dir := 1
+	if n > 0 {
+		dir = -1
 	}
+
+	for i := target; i != p.selected; i += dir {
+		if !p.items[i].Disabled {
+			p.selected = i
+			break
+		}
+	}
+
+	m.updateVisibility()
+}
+
+// SelectPageDown moves the selection down by a viewport's worth of items
+func (m *Menu) SelectPageDown() {
+	m.jump(m.pageSize())
+}
+
+// SelectPageUp moves the selection up by a viewport's worth of items
+func (m *Menu) SelectPageUp() {
+	m.jump(-m.pageSize())
 }
 
 // SetSelected sets the selected item in the menu
 func (m *Menu) SetSelected(i int) {
-	m.selected = i
+	m.current().selected = i
+	m.updateVisibility()
 }
 
 // GetSelected returns the selected item in the menu
 func (m *Menu) GetSelected() int {
-	return m.selected
+	return m.current().selected
 }
 
-// SelectNext selects the next item in the menu
-func (m *Menu) SelectNext() {
-	m.selected++
+// step moves the selection, skipping over disabled items
+func (m *Menu) step(dir int) {
+	p := m.current()
+	n := len(p.items)
+
+	for i := 1; i <= n; i++ {
+		next := ((p.selected+dir*i)%n + n) % n
 
-	if m.selected >= len(m.items) {
-		m.selected = 0
+		if !p.items[next].Disabled {
+			p.selected = next
+			break
+		}
 	}
 
 	m.updateVisibility()
 }
 
+// SelectNext selects the next item in the menu
+func (m *Menu) SelectNext() {
+	m.step(1)
+}
+
 // SelectPrev selects the previous item in the menu
 func (m *Menu) SelectPrev() {
-	m.selected--
+	m.step(-1)
+}
 
-	if m.selected < 0 {
-		m.selected = len(m.items) - 1
+// hoverItem selects an item the mouse has moved onto, if it can be selected
+func (m *Menu) hoverItem(p *page, i int) {
+	if p != m.current() || p.items[i].Disabled {
+		return
 	}
 
+	p.selected = i
 	m.updateVisibility()
 }
 
-// SelectByMouseY selects based on the Y position given
-func (m *Menu) SelectByMouseY(y int32) {
-	root := m.RootEntity
+// clickItem records a click on an item, if it can be chosen
+func (m *Menu) clickItem(p *page, i int) {
+	if p != m.current() || p.items[i].Disabled {
+		return
+	}
 
-	y += root.WorldToEntity.Y
+	m.clicked = i
+}
 
-	for i := range m.items {
-		eIndex := i * 2
+// SelectByMouse selects the item the mouse has moved onto, if any
+func (m *Menu) SelectByMouse(x, y int32) {
+	m.mouse.Move(m.viewEntity, x, y)
+}
 
-		child := root.GetChild(eIndex)
+// SelectByMouseClick records which item, if any, was clicked on
+func (m *Menu) SelectByMouseClick(x, y int32) {
+	m.clicked = -1
+	m.mouse.Click(m.viewEntity, x, y)
+}
 
-		if y >= child.Y && y <= child.Y+child.H {
-			m.selected = i
-			m.updateVisibility()
-			break
-		}
+// GetClicked gets the most recently clicked menu entry
+func (m *Menu) GetClicked() int {
+	return m.clicked
+}
+
+// setValue changes a selector or toggle value and tells whoever's listening
+func (m *Menu) setValue(info *itemInfo, value int) {
+	info.Value = value
+	m.renderItem(info)
+
+	if info.OnChange != nil {
+		info.OnChange(value)
 	}
 }
 
-// SelectByMouseClickY selects based on the Y position given
-func (m *Menu) SelectByMouseClickY(y int32) {
-	root := m.RootEntity
+// change steps a selector or flips a toggle
+func (m *Menu) change(info *itemInfo, dir int) {
+	switch {
+	case info.Toggle:
+		m.setValue(info, 1-info.Value)
+	case len(info.Values) > 0:
+		n := len(info.Values)
+		m.setValue(info, ((info.Value+dir)%n+n)%n)
+	}
+}
 
-	y += root.WorldToEntity.Y
+// SelectLeft moves the selected selector to its previous value, or flips
+// a toggle
+func (m *Menu) SelectLeft() {
+	p := m.current()
+	m.change(p.items[p.selected], -1)
+}
 
-	m.clicked = -1
+// SelectRight moves the selected selector to its next value, or flips a
+// toggle
+func (m *Menu) SelectRight() {
+	p := m.current()
+	m.change(p.items[p.selected], 1)
+}
+
+// activate does whatever an item does when chosen
+func (m *Menu) activate(info *itemInfo) bool {
+	if info.Disabled {
+		return false
+	}
+
+	m.change(info, 1)
+
+	switch {
+	case info.submenu != nil:
+		m.current().entity.Visible = false
+		m.path = append(m.path, info.submenu)
+		info.submenu.entity.Visible = true
+		info.submenu.selected = info.submenu.firstEnabled()
+		m.updateVisibility()
+		m.Show()
+
+	case info.Back:
+		m.Back()
+	}
+
+	if info.Action != nil {
+		return info.Action()
+	}
 
-	for i := range m.items {
-		eIndex := i * 2
+	return false
+}
 
-		child := root.GetChild(eIndex)
+// Activate chooses the selected item. It returns true if the item's action
+// wants to exit the game.
+func (m *Menu) Activate() bool {
+	p := m.current()
+	return m.activate(p.items[p.selected])
+}
 
-		if y >= child.Y && y <= child.Y+child.H {
-			m.clicked = i
+// ActivateClicked chooses the most recently clicked item, if any. It returns
+// true if the item's action wants to exit the game.
+func (m *Menu) ActivateClicked() bool {
+	if m.clicked < 0 {
+		return false
+	}
+
+	p := m.current()
+	p.selected = m.clicked
+	m.updateVisibility()
+
+	return m.activate(p.items[m.clicked])
+}
+
+// Back closes the current submenu. It returns false if already at the top
+// level, so the caller can decide what going back from there means.
+func (m *Menu) Back() bool {
+	if len(m.path) == 1 {
+		return false
+	}
+
+	m.current().entity.Visible = false
+	m.path = m.path[:len(m.path)-1]
+	m.current().entity.Visible = true
+	m.updateVisibility()
+	m.Show()
+
+	return true
+}
+
+// Reset closes any submenus and selects the first item
+func (m *Menu) Reset() {
+	for m.Back() {
+	}
+
+	m.SetSelected(m.current().firstEnabled())
+	m.Show()
+}
+
+// Free releases all the surfaces the menu rendered, for menus that get
+// rebuilt. The menu can't be used afterward.
+func (m *Menu) Free() {
+	var free func(p *page)
+	free = func(p *page) {
+		for _, info := range p.items {
+			info.entity.Surface.Free()
+			delete(m.am.Surfaces, info.surfaceID)
+
+			// The first shade is the plain highlight surface
+			for _, shade := range info.shades {
+				shade.Free()
+			}
+			delete(m.am.Surfaces, info.surfaceID+"-hi")
+
+			if info.submenu != nil {
+				free(info.submenu)
+			}
 		}
 	}
+
+	free(m.path[0])
+
+	for _, e := range []*scenegraph.Entity{m.upEntity, m.downEntity, m.anim.cursor} {
+		e.Surface.Free()
+	}
+
+	delete(m.am.Surfaces, m.id+"-up")
+	delete(m.am.Surfaces, m.id+"-down")
+	delete(m.am.Surfaces, m.id+"-cursor")
 }
 
-// GetClicked gets the most recently clicked menu entry
-func (m *Menu) GetClicked() int {
-	return m.clicked
+// find looks up an item by ID on any page
+func (m *Menu) find(id string) *itemInfo {
+	var search func(p *page) *itemInfo
+	search = func(p *page) *itemInfo {
+		for _, info := range p.items {
+			if info.ID == id {
+				return info
+			}
+			if info.submenu != nil {
+				if found := search(info.submenu); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+
+	info := search(m.path[0])
+	if info == nil {
+		panic(fmt.Sprintf("Menu %s: no item %q", m.id, id))
+	}
+
+	return info
+}
+
+// SetDisabled enables or disables an item by ID. If the selected item gets
+// disabled, the selection moves along.
+func (m *Menu) SetDisabled(id string, disabled bool) {
+	info := m.find(id)
+
+	if info.Disabled == disabled {
+		return
+	}
+
+	info.Disabled = disabled
+	m.renderItem(info)
+
+	p := m.current()
+	if disabled && p.items[p.selected] == info {
+		m.step(1)
+	}
+}
+
+// SetValue sets a selector or toggle value by ID, without calling OnChange.
+// It panics if the value is out of range for the item.
+func (m *Menu) SetValue(id string, value int) {
+	info := m.find(id)
+
+	n := len(info.Values)
+	if info.Toggle {
+		n = 2
+	}
+
+	if n > 0 && (value < 0 || value >= n) {
+		panic(fmt.Sprintf("Menu %s: SetValue %q: %d out of range", m.id, id, value))
+	}
+
+	info.Value = value
+	m.renderItem(info)
+}
+
+// GetValue returns a selector or toggle value by ID
+func (m *Menu) GetValue(id string) int {
+	return m.find(id).Value
+}
+
+// GetValueText returns the label of a selector's current value by ID
+func (m *Menu) GetValueText(id string) string {
+	info := m.find(id)
+
+	if len(info.Values) == 0 {
+		return ""
+	}
+
+	return info.Values[info.Value]
+}
+
+// GetValues returns the choices of a selector by ID
+func (m *Menu) GetValues(id string) []string {
+	return m.find(id).Values
 }
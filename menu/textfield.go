@@ -0,0 +1,328 @@
+package menu
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Character sets for TextField.Allowed
+const (
+	TextFieldPrintable    = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+	TextFieldAlphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+	TextFieldDigits       = "0123456789"
+)
+
+const (
+	textFieldCaret      = "_"
+	textFieldBlinkDelay = 500 // ms
+)
+
+// TextField is a one-line text entry widget. Typing comes in through SDL
+// TextInput events. Controllers get an arcade-style letter picker instead:
+// up and down roll through the allowed characters, right or A takes the
+// letter, and left or B backs up.
+type TextField struct {
+	fontID         string
+	color, hiColor sdl.Color
+
+	am   *assetmanager.AssetManager
+	text []rune
+
+	// Allowed lists the characters that may be entered, in picker order
+	Allowed string
+
+	// MaxLength is the most characters the field holds
+	MaxLength int
+
+	picking   bool // letter picker is in use
+	pickIndex int  // position of the picker letter in Allowed
+
+	blinkStart uint32
+	active     bool
+
+	textEntity, caretEntity *scenegraph.Entity
+	justification           int
+
+	RootEntity *scenegraph.Entity
+}
+
+// NewTextField constructs a text field. It's sized to fit maxLength of the
+// widest allowed character, so it can be positioned once and left alone.
+func NewTextField(am *assetmanager.AssetManager, fontID string, color, hiColor sdl.Color, maxLength int, allowed string, justification int) *TextField {
+	tf := &TextField{
+		fontID:        fontID,
+		color:         color,
+		hiColor:       hiColor,
+		am:            am,
+		Allowed:       allowed,
+		MaxLength:     maxLength,
+		justification: justification,
+	}
+
+	tf.textEntity = scenegraph.NewEntity(nil)
+	tf.caretEntity = scenegraph.NewEntity(nil)
+
+	tf.RootEntity = scenegraph.NewEntity(nil)
+	tf.RootEntity.AddChild(tf.textEntity, tf.caretEntity)
+
+	font := am.Fonts[fontID]
+
+	widest := 0
+	for _, r := range allowed + textFieldCaret {
+		w, _, err := font.SizeUTF8(string(r))
+		if err != nil {
+			panic(fmt.Sprintf("Text field size: %v", err))
+		}
+		if w > widest {
+			widest = w
+		}
+	}
+
+	tf.RootEntity.W = int32(widest * (maxLength + 1))
+	tf.RootEntity.H = int32(font.Height())
+
+	tf.render()
+
+	return tf
+}
+
+// renderEntity puts text on an entity, hiding it if there's nothing to show
+func (tf *TextField) renderEntity(entity *scenegraph.Entity, text string, color sdl.Color) {
+	if entity.Surface != nil {
+		entity.Surface.Free()
+		entity.Surface = nil
+	}
+
+	entity.W = 0
+	entity.H = 0
+	entity.Visible = text != ""
+
+	if text == "" {
+		return
+	}
+
+	surface, err := util.RenderText(tf.am.Fonts[tf.fontID], text, color)
+	if err != nil {
+		panic(fmt.Sprintf("Text field render: %v", err))
+	}
+
+	entity.Surface = surface
+	entity.W = surface.W
+	entity.H = surface.H
+	entity.MarkDirty()
+}
+
+// render redraws the text and caret and lines them up
+func (tf *TextField) render() {
+	tf.renderEntity(tf.textEntity, string(tf.text), tf.color)
+
+	caret := textFieldCaret
+	if tf.picking {
+		caret = string([]rune(tf.Allowed)[tf.pickIndex])
+	}
+
+	if tf.full() {
+		caret = ""
+	}
+
+	tf.renderEntity(tf.caretEntity, caret, tf.hiColor)
+
+	w := tf.textEntity.W + tf.caretEntity.W
+
+	var x int32
+
+	switch tf.justification {
+	case MenuJustifyLeft:
+		x = 0
+	case MenuJustifyCenter:
+		x = (tf.RootEntity.W - w) / 2
+	case MenuJustifyRight:
+		x = tf.RootEntity.W - w
+	}
+
+	tf.textEntity.X = x
+	tf.caretEntity.X = x + tf.textEntity.W
+
+	// Show the caret right away after any change so it's easy to follow
+	tf.blinkStart = sdl.GetTicks()
+}
+
+// full reports whether there's no room for more characters
+func (tf *TextField) full() bool {
+	return len(tf.text) >= tf.MaxLength
+}
+
+// allowed reports whether a character may be entered
+func (tf *TextField) allowed(r rune) bool {
+	return strings.ContainsRune(tf.Allowed, r)
+}
+
+// SetText replaces the contents of the field. Characters that aren't allowed
+// are dropped.
+func (tf *TextField) SetText(text string) {
+	tf.text = tf.text[:0]
+	tf.picking = false
+
+	tf.insert(text)
+}
+
+// GetText returns the contents of the field
+func (tf *TextField) GetText() string {
+	return string(tf.text)
+}
+
+// insert adds characters at the end, as many as are allowed and fit
+func (tf *TextField) insert(text string) {
+	for _, r := range text {
+		if tf.full() {
+			break
+		}
+
+		if tf.allowed(r) {
+			tf.text = append(tf.text, r)
+		}
+	}
+
+	tf.render()
+}
+
+// Backspace erases the last character
+func (tf *TextField) Backspace() {
+	if len(tf.text) > 0 {
+		tf.text = tf.text[:len(tf.text)-1]
+	}
+
+	tf.render()
+}
+
+// Start begins taking text input
+func (tf *TextField) Start() {
+	tf.active = true
+	tf.render()
+
+	sdl.StartTextInput()
+}
+
+// Stop ends text input
+func (tf *TextField) Stop() {
+	tf.active = false
+	tf.picking = false
+
+	sdl.StopTextInput()
+}
+
+// PickNext rolls the letter picker forward
+func (tf *TextField) PickNext() {
+	tf.pick(1)
+}
+
+// PickPrev rolls the letter picker backward
+func (tf *TextField) PickPrev() {
+	tf.pick(-1)
+}
+
+// pick starts the picker or moves it along
+func (tf *TextField) pick(dir int) {
+	n := len([]rune(tf.Allowed))
+
+	if n == 0 || tf.full() {
+		return
+	}
+
+	if tf.picking {
+		tf.pickIndex = (tf.pickIndex + dir + n) % n
+	} else {
+		tf.picking = true
+	}
+
+	tf.render()
+}
+
+// PickAccept adds the picker letter to the text
+func (tf *TextField) PickAccept() {
+	if !tf.picking {
+		tf.pick(0)
+		return
+	}
+
+	// Stay in picker mode, on the same letter, since names often repeat
+	tf.insert(string([]rune(tf.Allowed)[tf.pickIndex]))
+}
+
+// PickBack erases the last character from the picker
+func (tf *TextField) PickBack() {
+	tf.Backspace()
+}
+
+// textInputString pulls the typed text out of an event
+func textInputString(event *sdl.TextInputEvent) string {
+	text := event.Text[:]
+
+	if end := bytes.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+
+	return string(text)
+}
+
+// HandleEvent deals with typing and controller buttons. It returns true if it
+// used the event. Return and Escape are left for the caller.
+func (tf *TextField) HandleEvent(event *sdl.Event) bool {
+	if !tf.active {
+		return false
+	}
+
+	switch event := (*event).(type) {
+	case *sdl.TextInputEvent:
+		// Typing takes over from the picker
+		tf.picking = false
+		tf.insert(textInputString(event))
+		return true
+
+	case *sdl.KeyboardEvent:
+		if event.Type == sdl.KEYDOWN && event.Keysym.Sym == sdl.K_BACKSPACE {
+			tf.Backspace()
+			return true
+		}
+
+	case *sdl.ControllerButtonEvent:
+		if event.Type != sdl.CONTROLLERBUTTONDOWN {
+			return false
+		}
+
+		switch event.Button {
+		case sdl.CONTROLLER_BUTTON_DPAD_UP:
+			tf.PickPrev()
+		case sdl.CONTROLLER_BUTTON_DPAD_DOWN:
+			tf.PickNext()
+		case sdl.CONTROLLER_BUTTON_DPAD_RIGHT, sdl.CONTROLLER_BUTTON_A:
+			tf.PickAccept()
+		case sdl.CONTROLLER_BUTTON_DPAD_LEFT, sdl.CONTROLLER_BUTTON_B:
+			tf.PickBack()
+		default:
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// Update blinks the caret. Call it every frame; the caller should use a
+// timeout-driven event mode so there are frames to blink with.
+func (tf *TextField) Update() {
+	if !tf.active {
+		tf.caretEntity.Visible = false
+		return
+	}
+
+	elapsed := sdl.GetTicks() - tf.blinkStart
+
+	tf.caretEntity.Visible = tf.caretEntity.Surface != nil && (elapsed/textFieldBlinkDelay)%2 == 0
+}
@@ -0,0 +1,205 @@
+// Package nameentrystate asks for the player's name after a game that made the
+// high score table, and records it.
+package nameentrystate
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/highscore"
+	"github.com/beejjorgensen/eggdrop/menu"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	defaultName = "PLAYER"
+
+	// Characters allowed in names, in letter picker order
+	nameAllowed = menu.TextFieldAlphanumeric + "abcdefghijklmnopqrstuvwxyz.-!"
+)
+
+// NameEntryState holds all information about the name entry state
+type NameEntryState struct {
+	assetManager         *assetmanager.AssetManager
+	rootEntity           *scenegraph.Entity
+	scoreEntity          *scenegraph.Entity
+	bgColor              uint32
+	fontColor, nameColor sdl.Color
+
+	name *menu.TextField
+}
+
+// Init initializes this gamestate
+func (ns *NameEntryState) Init() {
+	// Create colors
+	ns.bgColor = sdl.MapRGB(gamecontext.GContext.PixelFormat, 40, 60, 120)
+	ns.fontColor = sdl.Color{R: 255, G: 255, B: 255, A: 255}
+	ns.nameColor = sdl.Color{R: 255, G: 255, B: 0, A: 255}
+
+	ns.assetManager = assetmanager.New()
+
+	err := ns.assetManager.LoadJSON("nameentryassets.json")
+	if err != nil {
+		panic(fmt.Sprintf("nameentryassets.json: %v", err))
+	}
+
+	ns.buildScene()
+}
+
+func (ns *NameEntryState) buildScene() {
+	am := ns.assetManager // asset manager
+
+	rootEntity := scenegraph.NewEntity(nil)
+	rootEntity.W = gamecontext.GContext.MainSurface.W
+	rootEntity.H = gamecontext.GContext.MainSurface.H
+
+	titleEntity := scenegraph.NewEntity(am.Surfaces["newHighScoreText"])
+	promptEntity := scenegraph.NewEntity(am.Surfaces["promptText"])
+	helpEntity := scenegraph.NewEntity(am.Surfaces["helpText"])
+
+	// Score gets filled in when the state is shown
+	ns.scoreEntity = scenegraph.NewEntity(nil)
+
+	ns.name = menu.NewTextField(am, "nameFont", ns.nameColor, ns.nameColor, highscore.MaxNameLength, nameAllowed, menu.MenuJustifyCenter)
+
+	rootEntity.AddChild(titleEntity, ns.scoreEntity, promptEntity, ns.name.RootEntity, helpEntity)
+
+	ns.rootEntity = rootEntity
+
+	scenegraph.CenterEntityInParent(titleEntity, rootEntity)
+	titleEntity.Y = 40
+
+	ns.scoreEntity.Y = 140
+
+	scenegraph.CenterEntityInParent(promptEntity, rootEntity)
+	promptEntity.Y = 250
+
+	scenegraph.CenterEntityInParent(ns.name.RootEntity, rootEntity)
+	ns.name.RootEntity.Y = 320
+
+	scenegraph.CenterEntityInParent(helpEntity, rootEntity)
+	helpEntity.Y = 500
+}
+
+// setText renders text into an entity and centers it
+func (ns *NameEntryState) setText(entity *scenegraph.Entity, font, text string, color sdl.Color) {
+	surface, err := util.RenderText(ns.assetManager.Fonts[font], text, color)
+	if err != nil {
+		panic(fmt.Sprintf("Name entry render: %v", err))
+	}
+
+	if entity.Surface != nil {
+		entity.Surface.Free()
+	}
+
+	entity.Surface = surface
+	entity.W = surface.W
+	entity.H = surface.H
+
+	scenegraph.CenterEntityInParent(entity, ns.rootEntity)
+}
+
+// save records the score in the high score table. Failing to save is no
+// reason to crash the game, so it just complains.
+func (ns *NameEntryState) save() {
+	result := gamecontext.GContext.LastGame
+
+	name := ns.name.GetText()
+	if name == "" {
+		name = defaultName
+	}
+
+	table, err := highscore.LoadDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "high scores: %v\n", err)
+		return
+	}
+
+	table.Add(highscore.Entry{
+		Name:  name,
+		Score: result.Score,
+		Level: result.Level,
+		Seed:  result.Seed,
+		Daily: result.Daily,
+		Date:  time.Now(),
+	})
+
+	if err := table.SaveDefault(); err != nil {
+		fmt.Fprintf(os.Stderr, "high scores: %v\n", err)
+	}
+}
+
+// HandleEvent handles SDL events for the name entry state
+func (ns *NameEntryState) HandleEvent(event *sdl.Event) bool {
+	if ns.name.HandleEvent(event) {
+		return false
+	}
+
+	switch event := (*event).(type) {
+	case *sdl.KeyboardEvent:
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
+		switch event.Keysym.Sym {
+
+		case sdl.K_ESCAPE:
+			// Don't want to be remembered
+			gamemanager.GGameManager.SetMode(gamemanager.GameModeGameOver)
+
+		case sdl.K_RETURN:
+			ns.save()
+			gamemanager.GGameManager.SetMode(gamemanager.GameModeGameOver)
+		}
+
+	case *sdl.ControllerButtonEvent:
+		if event.Type == sdl.CONTROLLERBUTTONDOWN && event.Button == sdl.CONTROLLER_BUTTON_START {
+			ns.save()
+			gamemanager.GGameManager.SetMode(gamemanager.GameModeGameOver)
+		}
+	}
+
+	return false
+}
+
+// Render renders the name entry state
+func (ns *NameEntryState) Render(mainWindowSurface *sdl.Surface) {
+	ns.name.Update()
+
+	mainWindowSurface.FillRect(nil, ns.bgColor)
+	ns.rootEntity.Render(mainWindowSurface)
+}
+
+// WillShow is called just before this state begins
+func (ns *NameEntryState) WillShow() {
+	ns.setText(ns.scoreEntity, "scoreFont", fmt.Sprintf("SCORE %d", gamecontext.GContext.LastGame.Score), ns.fontColor)
+
+	// call this to move on to the next transition state
+	gamemanager.GGameManager.WillShowComplete()
+}
+
+// WillHide is called just before this state ends
+func (ns *NameEntryState) WillHide() {
+	ns.name.Stop()
+}
+
+// DidShow is called just after this state begins
+func (ns *NameEntryState) DidShow() {
+	// Wake up now and then to blink the caret
+	gamemanager.GGameManager.SetEventMode(gamemanager.GameManagerEventTimeoutDriven)
+
+	util.SetMouseCapture(gamecontext.GContext.MainWindow, util.MouseCaptureNone)
+
+	// The name from last time is kept, since it's probably the same person
+	ns.name.Start()
+}
+
+// DidHide is called just after this state ends
+func (ns *NameEntryState) DidHide() {
+}
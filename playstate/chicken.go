@@ -1,25 +1,18 @@
 package playstate
 
 import (
+	"fmt"
 	"math"
-	"math/rand"
-
-	"github.com/beejjorgensen/eggdrop/gamemanager"
-)
-
-const (
-	chixInitAngleSpeed     = 0.001 // Higher is faster motion
-	chixInitAngleSpeedMult = 1.2   // How much higher to go each level, multiplier
-	chixFeetChangeDist     = 10    // How many pixels to move before changing feet
 )
 
 // chixInfo holds the chicken state
 type chixInfo struct {
+	x            float64 // left edge, px
 	pos, prevPos int32
 	footDist     int32
-	footNum      int
-	angleSpeed   float64
-	angle        float64
+	speed        float64
+	mover        ChickenMover
+	context      ChickenContext
 	Direction    int
 }
 
@@ -30,59 +23,74 @@ func (ps *PlayState) initChix() {
 
 // resetChix restores the chicken to the start state
 func (ps *PlayState) resetChix() {
-	ps.chix.pos = 0
-	ps.chix.prevPos = 0
-	ps.chix.footNum = 0
-	ps.chix.angleSpeed = chixInitAngleSpeed
-
-	// The chicken will be centered at multiples of 2π, so we choose one of 100000
-	// of those arbitrarily
-	ps.chix.angle = float64(rand.Int31n(100000)*2) * math.Pi
+	ps.chix.x = float64(ps.rootEntity.W-ps.chixEntity.W) / 2
+	ps.chix.pos = int32(ps.chix.x)
+	ps.chix.prevPos = ps.chix.pos
+
+	for _, legs := range ps.chixLegEntity {
+		legs.Anim.SetFrame(0)
+	}
 }
 
-// updateChix updates and positions the chicken
-func (ps *PlayState) updateChix() {
-	// Use the optimal frame delay to update positions
-	frameDelay := gamemanager.GGameManager.FrameDelay
+// chixContext fills out what the chicken mover needs to know this frame
+func (ps *PlayState) chixContext() *ChickenContext {
+	ctx := &ps.chix.context
+
+	ctx.DT = ps.frameTime
+	ctx.Speed = ps.chix.speed
+	ctx.X = ps.chix.x
+	ctx.MinX = 0
+	ctx.MaxX = float64(ps.rootEntity.W - ps.chixEntity.W)
+	ctx.NestX = float64(ps.nestEntity.X + (ps.nestEntity.W-ps.chixEntity.W)/2)
+	ctx.Level = ps.levelInfo()
+	ctx.Rand = ps.rng
+
+	ctx.TimeToLay = 0
+	if ps.eggLaunchDelay > ps.eggTimeSinceLaunch {
+		ctx.TimeToLay = ps.eggLaunchDelay - ps.eggTimeSinceLaunch
+	}
 
-	β := ps.chix.angle + float64(frameDelay)*ps.chix.angleSpeed
+	return ctx
+}
 
-	// Compute angle [-1..1]
-	// I just made up these numbers. They probably don't interfere nearly as much
-	// as I hope they do
-	pos := (math.Sin(β) + math.Sin(β*2.5) + math.Sin(β*4.7)) / 3.0
+// setChixMover switches to the movement pattern for the current level
+func (ps *PlayState) setChixMover() {
+	var err error
 
-	// Remap to screen
-	halfScreen := ps.rootEntity.W / 2
-	posInt := int32(pos*float64(halfScreen)*0.85) + halfScreen // range over 85% of screen width
+	ps.chix.speed = ps.levelInfo().ChickenSpeed
 
-	ps.chix.pos = posInt - ps.chixEntity.W/2
+	ps.chix.mover, err = newChickenMover(ps.levelInfo())
+	if err != nil {
+		// Should have been caught when the levels were loaded
+		panic(fmt.Sprintf("level %d: %v", ps.level, err))
+	}
+
+	ps.chix.mover.Reset(ps.chixContext())
+}
 
-	// Show left or right chix
+// updateChix updates and positions the chicken
+func (ps *PlayState) updateChix() {
+	ps.chix.x = ps.chix.mover.Move(ps.chixContext())
+
+	ps.chix.pos = int32(ps.chix.x)
+
+	// Show left or right chix. If it's standing still, it keeps facing the
+	// same way.
 	movingDist := ps.chix.pos - ps.chix.prevPos
-	movingRight := movingDist > 0
-	ps.chixLeftEntity.Visible = !movingRight
-	ps.chixRightEntity.Visible = movingRight
-	ps.chix.Direction = int(movingDist)
+	if movingDist != 0 {
+		movingRight := movingDist > 0
+		ps.chixLeftEntity.Visible = !movingRight
+		ps.chixRightEntity.Visible = movingRight
+		ps.chix.Direction = int(movingDist)
+	}
 
-	// Show feet 0 or feet 1
+	// Walk the legs
 	ps.chix.footDist += int32(math.Abs(float64(movingDist)))
 
-	if ps.chix.footDist > chixFeetChangeDist {
-		// next foot
-		ps.chix.footNum = (ps.chix.footNum + 1) % 2
-
-		switch ps.chix.footNum {
-		case 0: // Leg 0
-			ps.chixLegEntity[0].Visible = true  // left 0
-			ps.chixLegEntity[1].Visible = false // left 1
-			ps.chixLegEntity[2].Visible = true  // right 0
-			ps.chixLegEntity[3].Visible = false // right 1
-		case 1: // Leg 1
-			ps.chixLegEntity[0].Visible = false // left 0
-			ps.chixLegEntity[1].Visible = true  // left 1
-			ps.chixLegEntity[2].Visible = false // right 0
-			ps.chixLegEntity[3].Visible = true  // right 1
+	if ps.chix.footDist > ps.Levels.ChickenFeetChangeDist {
+		// Walking is driven by distance, not time, so just step along
+		for _, legs := range ps.chixLegEntity {
+			legs.Anim.Step()
 		}
 
 		// reset
@@ -91,6 +99,4 @@ func (ps *PlayState) updateChix() {
 
 	ps.chixEntity.X = ps.chix.pos
 	ps.chix.prevPos = ps.chix.pos
-
-	ps.chix.angle = β
 }
@@ -0,0 +1,283 @@
+package playstate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Chicken movement patterns for LevelInfo.Pattern
+const (
+	chixPatternSine      = "sine"      // blend of sine waves
+	chixPatternWalk      = "walk"      // random walk with pauses
+	chixPatternFeint     = "feint"     // turns around just before laying
+	chixPatternTrack     = "track"     // heads toward the nest
+	chixPatternEvade     = "evade"     // heads away from the nest
+	chixPatternWaypoints = "waypoints" // follows LevelInfo.Waypoints
+)
+
+const (
+	chixPixelsPerSpeed = 400000 // px/s for each unit of LevelInfo.ChickenSpeed
+	chixRange          = 0.85   // fraction of the screen the sine blend covers
+
+	chixWalkPauseMin = 200 // ms
+	chixWalkPauseMax = 900 // ms
+
+	chixFeintWarning = 250 // ms before laying to turn around
+	chixTrackLag     = 3.0 // 1/s, how eagerly the tracker closes the gap
+)
+
+// Waypoint is a stop on a scripted chicken path. X runs from 0 (left edge of
+// the chicken's range) to 1 (right edge).
+type Waypoint struct {
+	X     float64
+	Pause uint32 // ms to wait on arrival
+}
+
+// ChickenContext is what a ChickenMover knows about the world
+type ChickenContext struct {
+	DT         uint32  // ms of game time this frame
+	Speed      float64 // the level's ChickenSpeed
+	X          float64 // current chicken left edge, px
+	MinX, MaxX float64 // range of legal left edge positions, px
+	NestX      float64 // nest center, px, in chicken left edge terms
+	TimeToLay  uint32  // ms until the next egg drops
+	Level      *LevelInfo
+	Rand       *rand.Rand // all random choices must come from here
+}
+
+// pixelSpeed converts the level speed to px/s
+func (c *ChickenContext) pixelSpeed() float64 {
+	return c.Speed * chixPixelsPerSpeed
+}
+
+// clamp keeps a position in the legal range
+func (c *ChickenContext) clamp(x float64) float64 {
+	return math.Max(c.MinX, math.Min(c.MaxX, x))
+}
+
+// ChickenMover decides where the chicken goes. Movers only pick the position;
+// the facing and leg animation follow from however far it moved.
+type ChickenMover interface {
+	// Reset starts the pattern from the chicken's current position
+	Reset(ctx *ChickenContext)
+
+	// Move returns the new left edge of the chicken, px
+	Move(ctx *ChickenContext) float64
+}
+
+// newChickenMover builds the mover for a level's pattern
+func newChickenMover(level *LevelInfo) (ChickenMover, error) {
+	switch level.Pattern {
+	case chixPatternSine:
+		return &sineMover{}, nil
+	case chixPatternWalk:
+		return &walkMover{}, nil
+	case chixPatternFeint:
+		return &feintMover{}, nil
+	case chixPatternTrack:
+		return &trackMover{}, nil
+	case chixPatternEvade:
+		return &trackMover{evade: true}, nil
+	case chixPatternWaypoints:
+		if len(level.Waypoints) == 0 {
+			return nil, fmt.Errorf("pattern %q needs Waypoints", level.Pattern)
+		}
+		return &waypointMover{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown Pattern %q", level.Pattern)
+}
+
+// moveToward steps x toward target by at most step, reporting arrival
+func moveToward(x, target, step float64) (float64, bool) {
+	if math.Abs(target-x) <= step {
+		return target, true
+	}
+
+	return x + math.Copysign(step, target-x), false
+}
+
+// sineMover is the original chicken: a blend of sine waves
+type sineMover struct {
+	angle float64
+}
+
+// sinePos maps an angle to a position in [-1..1]. I just made up these
+// numbers. They probably don't interfere nearly as much as I hope they do.
+func sinePos(β float64) float64 {
+	return (math.Sin(β) + math.Sin(β*2.5) + math.Sin(β*4.7)) / 3.0
+}
+
+// sineToScreen maps [-1..1] to a chicken left edge
+func sineToScreen(ctx *ChickenContext, pos float64) float64 {
+	half := (ctx.MaxX - ctx.MinX) / 2
+	return ctx.MinX + half + pos*half*chixRange
+}
+
+// Reset picks a starting angle that puts the chicken close to where it
+// already is, so there's no jump between levels
+func (m *sineMover) Reset(ctx *ChickenContext) {
+	// The chicken will be centered at multiples of 2π, so we choose one of 100000
+	// of those arbitrarily
+	base := float64(ctx.Rand.Int31n(100000)*2) * math.Pi
+
+	m.angle = base
+	best := math.Inf(1)
+
+	for β := base; β < base+2*math.Pi; β += 0.005 {
+		dist := math.Abs(sineToScreen(ctx, sinePos(β)) - ctx.X)
+		if dist < best {
+			best = dist
+			m.angle = β
+		}
+	}
+}
+
+// Move advances along the sine blend
+func (m *sineMover) Move(ctx *ChickenContext) float64 {
+	m.angle += float64(ctx.DT) * ctx.Speed
+
+	return sineToScreen(ctx, sinePos(m.angle))
+}
+
+// walkMover wanders to random spots, stopping for a bit at each one
+type walkMover struct {
+	target float64
+	pause  uint32 // ms left to wait
+}
+
+// pickTarget chooses somewhere new to go
+func (m *walkMover) pickTarget(ctx *ChickenContext) {
+	m.target = ctx.MinX + ctx.Rand.Float64()*(ctx.MaxX-ctx.MinX)
+}
+
+// Reset starts with a new destination
+func (m *walkMover) Reset(ctx *ChickenContext) {
+	m.pause = 0
+	m.pickTarget(ctx)
+}
+
+// Move walks toward the target, or waits
+func (m *walkMover) Move(ctx *ChickenContext) float64 {
+	if m.pause > 0 {
+		if m.pause > ctx.DT {
+			m.pause -= ctx.DT
+		} else {
+			m.pause = 0
+			m.pickTarget(ctx)
+		}
+		return ctx.X
+	}
+
+	x, arrived := moveToward(ctx.X, m.target, ctx.pixelSpeed()*float64(ctx.DT)/1000)
+
+	if arrived {
+		m.pause = chixWalkPauseMin + uint32(ctx.Rand.Int31n(chixWalkPauseMax-chixWalkPauseMin))
+	}
+
+	return x
+}
+
+// feintMover paces back and forth, and turns around just before laying to
+// throw the player off
+type feintMover struct {
+	dir     float64
+	feinted bool // already turned around for this egg
+}
+
+// Reset picks a random direction
+func (m *feintMover) Reset(ctx *ChickenContext) {
+	m.dir = 1
+	if ctx.Rand.Intn(2) == 0 {
+		m.dir = -1
+	}
+	m.feinted = false
+}
+
+// Move paces, bouncing off the edges
+func (m *feintMover) Move(ctx *ChickenContext) float64 {
+	if ctx.TimeToLay <= chixFeintWarning {
+		if !m.feinted {
+			m.dir = -m.dir
+			m.feinted = true
+		}
+	} else {
+		m.feinted = false
+	}
+
+	x := ctx.X + m.dir*ctx.pixelSpeed()*float64(ctx.DT)/1000
+
+	if x <= ctx.MinX || x >= ctx.MaxX {
+		m.dir = -m.dir
+	}
+
+	return ctx.clamp(x)
+}
+
+// trackMover heads toward the nest, or away from it if evade is set
+type trackMover struct {
+	evade bool
+}
+
+// Reset has nothing to do
+func (m *trackMover) Reset(ctx *ChickenContext) {
+}
+
+// Move closes on (or flees) the nest, easing off as it gets close
+func (m *trackMover) Move(ctx *ChickenContext) float64 {
+	target := ctx.NestX
+
+	if m.evade {
+		// Run for whichever side is farther from the nest
+		if ctx.NestX > (ctx.MinX+ctx.MaxX)/2 {
+			target = ctx.MinX
+		} else {
+			target = ctx.MaxX
+		}
+	}
+
+	maxStep := ctx.pixelSpeed() * float64(ctx.DT) / 1000
+	step := math.Min(maxStep, math.Abs(target-ctx.X)*chixTrackLag*float64(ctx.DT)/1000)
+
+	x, _ := moveToward(ctx.X, target, step)
+
+	return ctx.clamp(x)
+}
+
+// waypointMover follows the level's scripted path, looping at the end
+type waypointMover struct {
+	next  int
+	pause uint32 // ms left to wait
+}
+
+// Reset starts from the first waypoint
+func (m *waypointMover) Reset(ctx *ChickenContext) {
+	m.next = 0
+	m.pause = 0
+}
+
+// Move walks to the next waypoint and waits there
+func (m *waypointMover) Move(ctx *ChickenContext) float64 {
+	if m.pause > 0 {
+		if m.pause > ctx.DT {
+			m.pause -= ctx.DT
+		} else {
+			m.pause = 0
+		}
+		return ctx.X
+	}
+
+	waypoints := ctx.Level.Waypoints
+	wp := waypoints[m.next]
+	target := ctx.MinX + wp.X*(ctx.MaxX-ctx.MinX)
+
+	x, arrived := moveToward(ctx.X, target, ctx.pixelSpeed()*float64(ctx.DT)/1000)
+
+	if arrived {
+		m.pause = wp.Pause
+		m.next = (m.next + 1) % len(waypoints)
+	}
+
+	return x
+}
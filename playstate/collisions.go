@@ -0,0 +1,19 @@
+package playstate
+
+import "github.com/beejjorgensen/eggdrop/collision"
+
+// Collision layers. Eggs and pickups each watch for the nest, and take care
+// of themselves when they land in it.
+const (
+	layerEgg = 1 << iota
+	layerNest
+	layerPowerUp
+)
+
+// initCollisions makes the collision world and puts the nest in it. Eggs and
+// pickups are added as they're made.
+func (ps *PlayState) initCollisions() {
+	ps.world = collision.NewWorld(collision.DefaultCellSize)
+
+	ps.world.Add(ps.nestEntity, layerNest, layerEgg|layerPowerUp, collision.Handlers{})
+}
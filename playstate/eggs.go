@@ -1,7 +1,7 @@
 package playstate
 
 import (
-	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/collision"
 	"github.com/beejjorgensen/eggdrop/scenegraph"
 )
 
@@ -9,62 +9,74 @@ const (
 	eggStartingY = 50  // pixels
 	eggSplatY    = 570 // pixels
 
-	eggSpeed0        = 250 // pixels per second
-	eggSpeedPerLevel = 100 // pixels per second, how much to increase per level
-
-	eggDelay0        = 500  // ms
-	eggDelayPerLevel = 0.85 // multiple
-
 	eggLaunchXOffset = 80 // px
 )
 
-// initEggs initializes the egg subsystem. It sounds so important when I call it
-// that.
-func (ps *PlayState) initEggs() {
-	ps.eggLaunchDelay = eggDelay0
+// eggInfo is a falling egg
+type eggInfo struct {
+	entity *scenegraph.Entity
+	kind   *EggType
 }
 
 // resetEggs hides all the eggs
 func (ps *PlayState) resetEggs() {
-	for _, egg := range ps.eggContainer.Children {
-		egg.Visible = false
+	ps.freeEggs = ps.freeEggs[:0]
+
+	for _, egg := range ps.eggs {
+		egg.entity.Visible = false
+		ps.freeEggs = append(ps.freeEggs, egg)
 	}
 }
 
-// newEgg creates a new egg and adds it to the egg container
-func (ps *PlayState) newEgg() *scenegraph.Entity {
-	egg := scenegraph.NewEntity(ps.assetManager.Surfaces["eggImage"])
-	egg.Visible = false
-	ps.eggContainer.AddChild(egg)
+// newEgg creates a new egg and adds it to the egg container and the
+// collision world
+func (ps *PlayState) newEgg() *eggInfo {
+	egg := &eggInfo{entity: scenegraph.NewEntity(nil)}
+	egg.entity.Visible = false
+
+	ps.eggContainer.AddChild(egg.entity)
+	ps.eggs = append(ps.eggs, egg)
+
+	ps.world.Add(egg.entity, layerEgg, layerNest, collision.Handlers{
+		Enter: func(self, other collision.Body) {
+			ps.catchEgg(egg)
+		},
+	})
 
 	return egg
 }
 
-// getEgg returns a ready-to-use egg, creating it if necessary. This is an O(N)
-// process, sorry.
-func (ps *PlayState) getEgg() *scenegraph.Entity {
-	var readyEgg *scenegraph.Entity
-
-	for _, egg := range ps.eggContainer.Children {
-		if !egg.Visible {
-			readyEgg = egg
-			break
-		}
+// getEgg returns a ready-to-use egg off the free list, creating it if
+// necessary
+func (ps *PlayState) getEgg() *eggInfo {
+	n := len(ps.freeEggs)
+	if n == 0 {
+		return ps.newEgg()
 	}
 
-	// If we didn't find one, make a new one
-	if readyEgg == nil {
-		readyEgg = ps.newEgg()
-	}
+	egg := ps.freeEggs[n-1]
+	ps.freeEggs = ps.freeEggs[:n-1]
+
+	return egg
+}
 
-	return readyEgg
+// retireEgg hides an egg and puts it back on the free list
+func (ps *PlayState) retireEgg(egg *eggInfo) {
+	egg.entity.Visible = false
+	ps.freeEggs = append(ps.freeEggs, egg)
 }
 
 // launchEgg brings a new egg into existence
 func (ps *PlayState) launchEgg() {
 	egg := ps.getEgg()
+	e := egg.entity
+
+	egg.kind = ps.chooseEggType()
+	e.Surface = egg.kind.surface
+	e.W = e.Surface.W
+	e.H = e.Surface.H
 
-	egg.Y = eggStartingY
+	e.Y = eggStartingY
 
 	var offset int32
 
@@ -74,49 +86,57 @@ func (ps *PlayState) launchEgg() {
 		offset = ps.chixEntity.W - eggLaunchXOffset
 	}
 
-	egg.X = ps.chixEntity.X + offset
+	e.X = ps.chixEntity.X + offset
+	e.ResetSweep()
+
+	e.Visible = true
+
+	burstAtEntity(ps.particles.feathers, e, eggStartingY)
 
-	egg.Visible = true
+	ps.maybeLaunchPowerUp(ps.chixEntity.X + ps.chixEntity.W/2)
 }
 
 // updateEggs animates eggs to their new position
 func (ps *PlayState) updateEggs() {
-	frameDelay := gamemanager.GGameManager.FrameDelay
+	frameDelay := ps.frameTime
 
 	// Drop new eggs
 	ps.eggTimeSinceLaunch += frameDelay
 	if ps.eggTimeSinceLaunch > ps.eggLaunchDelay {
 		ps.launchEgg()
 		ps.eggTimeSinceLaunch = 0
+		ps.eggLaunchDelay = ps.nextEggDelay()
 	}
 
 	// Animate eggs
 
-	speed := eggSpeed0 + ps.level*eggSpeedPerLevel // px per second
+	speed := ps.levelInfo().FallSpeed // px per second
 	dY := int32(speed * int(frameDelay) / 1000)
 
 	// Right now it just iterates through all eggs and animates the visible ones.
 	// This could be improved for efficiency.
-	for _, egg := range ps.eggContainer.Children {
-		if egg.Visible {
-			egg.MoveTo(egg.X, egg.Y+dY)
-
-			if egg.Y > eggSplatY {
-				egg.Visible = false
+	for _, egg := range ps.eggs {
+		e := egg.entity
+
+		if e.Visible {
+			e.MoveTo(e.X+ps.magnetPull(egg), e.Y+dY)
+
+			if e.Y > eggSplatY {
+				ps.retireEgg(egg)
+				if !egg.kind.NoSplat {
+					ps.splatEgg(e)
+				}
+				ps.scoreSplat(egg.kind)
 			}
 		}
 	}
 }
 
-// testEggCollision looks for collisions with the eggs and nest
-func (ps *PlayState) testEggCollision() {
-	for _, egg := range ps.eggContainer.Children {
-		if egg.Visible {
-			if egg.MoveAABB.TestCollision(&ps.nestEntity.MoveAABB) {
-				egg.Visible = false
-				//fmt.Printf("Hit!\n%#v\n%#v\n", egg.MoveAABB, ps.nestEntity.MoveAABB)
-			}
-		}
-	}
+// catchEgg puts an egg that landed in the nest away and scores it
+func (ps *PlayState) catchEgg(egg *eggInfo) {
+	e := egg.entity
 
+	ps.retireEgg(egg)
+	burstAtEntity(ps.particles.sparkles, e, e.Y+e.H/2)
+	ps.scoreCatch(egg.kind)
 }
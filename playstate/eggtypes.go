@@ -0,0 +1,111 @@
+package playstate
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// EggType describes how one kind of egg looks and what happens when it's
+// caught or hits the ground. Field names match the keys in levels.json.
+type EggType struct {
+	ID     string
+	Asset  string  // image asset ID for the egg
+	Weight float64 // default spawn weight, if a level doesn't override it
+
+	Quota bool // catching it counts toward the level quota
+
+	CatchPoints, CatchLives int
+	SplatPoints, SplatLives int
+	NoSplat                 bool // vanishes without leaving a splat
+
+	surface *sdl.Surface
+}
+
+// eggChance is a spawn weight for an egg type in a particular level
+type eggChance struct {
+	kind   *EggType
+	weight float64
+}
+
+// eggType returns the named egg type, or nil
+func (lf *LevelFile) eggType(id string) *EggType {
+	for i := range lf.EggTypes {
+		if lf.EggTypes[i].ID == id {
+			return &lf.EggTypes[i]
+		}
+	}
+
+	return nil
+}
+
+// buildEggChances works out the spawn weights for a level. Types are kept in
+// the order they appear in the file so that random choices are repeatable.
+func (lf *LevelFile) buildEggChances(l *LevelInfo) error {
+	for eggType := range l.EggTypes {
+		if lf.eggType(eggType) == nil {
+			return fmt.Errorf("unknown egg type %q", eggType)
+		}
+	}
+
+	l.eggChances = l.eggChances[:0]
+	l.eggTotalWeight = 0
+
+	for i := range lf.EggTypes {
+		kind := &lf.EggTypes[i]
+
+		weight, ok := l.EggTypes[kind.ID]
+		if !ok {
+			if l.EggTypes != nil {
+				// Level gave a list of types and this isn't on it
+				continue
+			}
+			weight = kind.Weight
+		}
+
+		if weight < 0 {
+			return fmt.Errorf("egg type %q has negative weight", kind.ID)
+		}
+		if weight == 0 {
+			continue
+		}
+
+		l.eggChances = append(l.eggChances, eggChance{kind: kind, weight: weight})
+		l.eggTotalWeight += weight
+	}
+
+	if l.eggTotalWeight <= 0 {
+		return fmt.Errorf("no egg types can spawn")
+	}
+
+	return nil
+}
+
+// initEggTypes looks up the egg type images now that the assets are loaded
+func (ps *PlayState) initEggTypes() {
+	for i := range ps.Levels.EggTypes {
+		kind := &ps.Levels.EggTypes[i]
+
+		kind.surface = ps.assetManager.Surfaces[kind.Asset]
+		if kind.surface == nil {
+			panic(fmt.Sprintf("%s: egg type %s: unknown Asset %q", levelsFile, kind.ID, kind.Asset))
+		}
+	}
+}
+
+// chooseEggType picks a random egg type according to the level's weights
+func (ps *PlayState) chooseEggType() *EggType {
+	info := ps.levelInfo()
+
+	r := ps.rng.Float64() * info.eggTotalWeight
+
+	for _, c := range info.eggChances {
+		if r < c.weight {
+			return c.kind
+		}
+		r -= c.weight
+	}
+
+	// Rounding error got us here, so it's the last one
+	return info.eggChances[len(info.eggChances)-1].kind
+}
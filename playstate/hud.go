@@ -0,0 +1,94 @@
+package playstate
+
+import (
+	"fmt"
+
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// hudText is a HUD entity showing a formatted value. The text is only
+// re-rendered when it changes.
+type hudText struct {
+	entity *scenegraph.Entity
+	font   *ttf.Font
+	format string
+	color  sdl.Color
+
+	text  string
+	valid bool // false until the first render
+}
+
+// hudInfo holds all the HUD elements
+type hudInfo struct {
+	score, level, eggs, lives hudText
+	powerUps                  hudText
+}
+
+// newHUDText builds a hudText for the entity with the given ID
+func (ps *PlayState) newHUDText(id, format string) hudText {
+	entity := ps.rootEntity.SearchByID(id)
+	if entity == nil {
+		panic(fmt.Sprintf("HUD: missing entity %s", id))
+	}
+
+	return hudText{
+		entity: entity,
+		font:   ps.assetManager.Fonts["hudFont"],
+		format: format,
+		color:  ps.fontNormalColor,
+	}
+}
+
+// set updates the displayed value, rendering only if it changed
+func (h *hudText) set(value interface{}) {
+	text := fmt.Sprintf(h.format, value)
+
+	if h.valid && h.text == text {
+		return
+	}
+
+	if h.entity.Surface != nil {
+		h.entity.Surface.Free()
+		h.entity.Surface = nil
+	}
+
+	h.text = text
+	h.valid = true
+
+	// Nothing to render, so just hide it
+	h.entity.Visible = text != ""
+	if !h.entity.Visible {
+		return
+	}
+
+	surface, err := util.RenderText(h.font, text, h.color)
+	if err != nil {
+		panic(fmt.Sprintf("HUD render text: %v", err))
+	}
+
+	h.entity.Surface = surface
+	h.entity.W = surface.W
+	h.entity.H = surface.H
+	h.entity.MarkDirty()
+}
+
+// initHUD finds the HUD entities declared in the scene graph
+func (ps *PlayState) initHUD() {
+	ps.hud.score = ps.newHUDText("hudScore", "SCORE %d")
+	ps.hud.level = ps.newHUDText("hudLevel", "LEVEL %d")
+	ps.hud.eggs = ps.newHUDText("hudEggs", "EGGS %d")
+	ps.hud.lives = ps.newHUDText("hudLives", "LIVES %d")
+	ps.hud.powerUps = ps.newHUDText("hudPowerUps", "%s")
+}
+
+// updateHUD refreshes the HUD values
+func (ps *PlayState) updateHUD() {
+	ps.hud.score.set(ps.score.score)
+	ps.hud.level.set(ps.level)
+	ps.hud.eggs.set(ps.eggsRemaining())
+	ps.hud.lives.set(ps.score.lives)
+	ps.hud.powerUps.set(ps.powerUpHUDText())
+}
@@ -0,0 +1,182 @@
+package playstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+)
+
+const (
+	levelsFile      = "levels.json"
+	levelClearBonus = 100 // points for clearing a level, times the level number
+)
+
+// LevelInfo holds the difficulty settings for a single level. Field names match
+// the keys in levels.json.
+type LevelInfo struct {
+	ChickenSpeed float64            // chicken angle speed, higher is faster
+	Pattern      string             // chicken movement pattern
+	Waypoints    []Waypoint         // path for the "waypoints" pattern
+	EggInterval  [2]uint32          // ms, min and max time between egg launches
+	FallSpeed    int                // px/s
+	EggTypes     map[string]float64 // spawn weights, overriding the egg type defaults
+	Quota        int                // eggs to catch to clear the level
+
+	PowerUpChance float64 // chance of a power-up dropping with each egg, 0-1
+
+	eggChances     []eggChance
+	eggTotalWeight float64
+}
+
+// LevelFile is the layout of levels.json
+type LevelFile struct {
+	ChickenFeetChangeDist int32 // px the chicken moves before changing feet
+	EggTypes              []EggType
+	Levels                []LevelInfo
+}
+
+// validate checks a level for nonsense values
+func (l *LevelInfo) validate() error {
+	if l.ChickenSpeed <= 0 {
+		return errors.New("ChickenSpeed must be positive")
+	}
+
+	if _, err := newChickenMover(l); err != nil {
+		return err
+	}
+
+	for i, wp := range l.Waypoints {
+		if wp.X < 0 || wp.X > 1 {
+			return fmt.Errorf("waypoint %d: X must be between 0 and 1", i+1)
+		}
+	}
+
+	if l.EggInterval[0] == 0 || l.EggInterval[1] < l.EggInterval[0] {
+		return errors.New("EggInterval must be [min, max] with 0 < min <= max")
+	}
+
+	if l.FallSpeed <= 0 {
+		return errors.New("FallSpeed must be positive")
+	}
+
+	if l.Quota <= 0 {
+		return errors.New("Quota must be positive")
+	}
+
+	if l.PowerUpChance < 0 || l.PowerUpChance > 1 {
+		return errors.New("PowerUpChance must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// LoadLevels reads a level file from the asset directory
+func LoadLevels(jsonFile string) (*LevelFile, error) {
+	jsonStr, err := ioutil.ReadFile(assetmanager.AssetPath(jsonFile))
+	if err != nil {
+		return nil, err
+	}
+
+	levelFile := &LevelFile{}
+
+	err = json.Unmarshal(jsonStr, levelFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if levelFile.ChickenFeetChangeDist <= 0 {
+		return nil, errors.New("ChickenFeetChangeDist must be positive")
+	}
+
+	if len(levelFile.EggTypes) == 0 {
+		return nil, errors.New("no EggTypes found")
+	}
+
+	for i := range levelFile.EggTypes {
+		if levelFile.EggTypes[i].ID == "" || levelFile.EggTypes[i].Asset == "" {
+			return nil, fmt.Errorf("egg type %d: needs Id and Asset", i+1)
+		}
+	}
+
+	if len(levelFile.Levels) == 0 {
+		return nil, errors.New("no Levels found")
+	}
+
+	for i := range levelFile.Levels {
+		level := &levelFile.Levels[i]
+
+		if err = level.validate(); err == nil {
+			err = levelFile.buildEggChances(level)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("level %d: %v", i+1, err)
+		}
+	}
+
+	return levelFile, nil
+}
+
+// initLevels loads the level file if one wasn't set
+func (ps *PlayState) initLevels() {
+	if ps.Levels != nil {
+		return
+	}
+
+	var err error
+
+	ps.Levels, err = LoadLevels(levelsFile)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", levelsFile, err))
+	}
+}
+
+// levelInfo returns the settings for the current level
+func (ps *PlayState) levelInfo() *LevelInfo {
+	return &ps.Levels.Levels[ps.level-1]
+}
+
+// nextEggDelay picks a random time until the next egg launch
+func (ps *PlayState) nextEggDelay() uint32 {
+	interval := ps.levelInfo().EggInterval
+	spread := int32(interval[1] - interval[0])
+
+	return interval[0] + uint32(ps.rng.Int31n(spread+1))
+}
+
+// startLevel sets up the given level and runs the interlude in front of it
+func (ps *PlayState) startLevel(level int) {
+	ps.level = level
+	ps.score.levelCaught = 0
+
+	ps.eggLaunchDelay = ps.nextEggDelay()
+	ps.eggTimeSinceLaunch = 0
+
+	ps.setChixMover()
+
+	ps.resetEggs()
+	ps.resetPowerUps()
+
+	ps.constructInterludeImage()
+	ps.setState(stateInterlude)
+}
+
+// checkLevelComplete moves on to the next level once the quota is caught
+func (ps *PlayState) checkLevelComplete() {
+	if ps.state.state != stateAction || ps.eggsRemaining() > 0 {
+		return
+	}
+
+	ps.score.score += levelClearBonus * ps.level
+
+	if ps.level == len(ps.Levels.Levels) {
+		// That was the last one
+		ps.won = true
+		ps.setState(stateGameOver)
+		return
+	}
+
+	ps.startLevel(ps.level + 1)
+}
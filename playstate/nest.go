@@ -0,0 +1,235 @@
+package playstate
+
+import (
+	"math"
+
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/util"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Mouse control modes for NestConfig.MouseMode
+const (
+	NestMouseDirect = iota // nest jumps straight to the cursor
+	NestMouseFollow        // nest eases toward the cursor at a capped speed
+)
+
+// Which input device last moved the nest
+const (
+	nestInputMouse = iota
+	nestInputKeyboard
+)
+
+// NestConfig holds the tunable nest movement parameters
+type NestConfig struct {
+	MouseMode int
+
+	MouseCapture     int     // util.MouseCapture* mode used while playing
+	MouseSensitivity float64 // multiplier on mouse motion while captured
+
+	Accel    float64 // px/s², keyboard acceleration
+	MaxSpeed float64 // px/s, keyboard top speed
+	Friction float64 // 1/s, fraction of speed lost per second with no keys down
+
+	FollowEase  float64 // 1/s, how quickly the nest closes the gap in follow mode
+	FollowSpeed float64 // px/s, top speed in follow mode
+}
+
+// DefaultNestConfig is used for whatever the PlayState NestConfig leaves empty
+var DefaultNestConfig = NestConfig{
+	MouseMode:        NestMouseDirect,
+	MouseCapture:     util.MouseCaptureRelative,
+	MouseSensitivity: 1.0,
+	Accel:            4000,
+	MaxSpeed:         800,
+	Friction:         10,
+	FollowEase:       12,
+	FollowSpeed:      1000,
+}
+
+// nestInfo holds the nest state
+type nestInfo struct {
+	x        float64 // left edge, px
+	velocity float64 // px/s
+	targetX  float64 // center of nest that the mouse is asking for
+	input    int
+	captured bool
+}
+
+// initNest fills in the config from DefaultNestConfig. An empty config gets
+// the defaults wholesale. Otherwise the modes are taken as given, since zero
+// is a real choice for them, and any zero speeds and rates are filled in so a
+// partial config doesn't leave the nest stuck.
+func (ps *PlayState) initNest() {
+	c := &ps.NestConfig
+	d := &DefaultNestConfig
+
+	if *c == (NestConfig{}) {
+		*c = *d
+		return
+	}
+
+	fill := func(v *float64, def float64) {
+		if *v == 0 {
+			*v = def
+		}
+	}
+
+	fill(&c.MouseSensitivity, d.MouseSensitivity)
+	fill(&c.Accel, d.Accel)
+	fill(&c.MaxSpeed, d.MaxSpeed)
+	fill(&c.Friction, d.Friction)
+	fill(&c.FollowEase, d.FollowEase)
+	fill(&c.FollowSpeed, d.FollowSpeed)
+}
+
+// resetNest stops the nest where it is
+func (ps *PlayState) resetNest() {
+	ps.nest.x = float64(ps.nestEntity.X)
+	ps.nest.velocity = 0
+	ps.nest.targetX = float64(ps.nestEntity.X + ps.nestEntity.W/2)
+	ps.nest.input = nestInputMouse
+	ps.hitFlash.Finish()
+}
+
+// captureMouse grabs or releases the mouse pointer according to the config
+func (ps *PlayState) captureMouse(capture bool) {
+	mode := util.MouseCaptureNone
+
+	if capture {
+		mode = ps.NestConfig.MouseCapture
+
+		// Relative motion picks up from wherever the nest is now
+		ps.nest.targetX = ps.nest.x + float64(ps.nestEntity.W)/2
+	}
+
+	util.SetMouseCapture(gamecontext.GContext.MainWindow, mode)
+
+	ps.nest.captured = capture && mode != util.MouseCaptureNone
+}
+
+// nestMinMaxX returns the range of legal nest X positions
+func (ps *PlayState) nestMinMaxX() (float64, float64) {
+	return 0, float64(gamecontext.GContext.WindowWidth - ps.nestEntity.W)
+}
+
+// positionNest positions and clamps the nest, x being the center of the nest
+func (ps *PlayState) positionNest(x int32) {
+	w := ps.nestEntity.W
+	x -= w / 2 // center
+
+	if x < 0 {
+		x = 0
+	}
+
+	maxX := gamecontext.GContext.WindowWidth - w
+	if x > maxX {
+		x = maxX
+	}
+
+	ps.nest.x = float64(x)
+
+	ps.nestEntity.MoveTo(x, ps.nestEntity.Y)
+}
+
+// nestMouseMoved records a new mouse position for the nest to head toward.
+// While the mouse is captured, the relative motion is used (scaled by the
+// sensitivity), since the absolute position is meaningless.
+func (ps *PlayState) nestMouseMoved(event *sdl.MouseMotionEvent) {
+	nest := &ps.nest
+
+	if nest.captured {
+		nest.targetX += float64(event.XRel) * ps.NestConfig.MouseSensitivity
+
+		// Don't let the target wander off where it would take ages to come back
+		half := float64(ps.nestEntity.W) / 2
+		minX, maxX := ps.nestMinMaxX()
+		nest.targetX = math.Max(minX+half, math.Min(maxX+half, nest.targetX))
+	} else {
+		nest.targetX = float64(event.X)
+	}
+
+	nest.input = nestInputMouse
+	nest.velocity = 0
+
+	if ps.NestConfig.MouseMode == NestMouseDirect {
+		ps.positionNest(int32(nest.targetX))
+	}
+}
+
+// nestKeyDirection returns -1, 0, or 1 depending on which arrow keys are down
+func nestKeyDirection() float64 {
+	keys := sdl.GetKeyboardState()
+
+	var dir float64
+
+	if keys[sdl.SCANCODE_LEFT] != 0 || keys[sdl.SCANCODE_A] != 0 {
+		dir--
+	}
+	if keys[sdl.SCANCODE_RIGHT] != 0 || keys[sdl.SCANCODE_D] != 0 {
+		dir++
+	}
+
+	return dir
+}
+
+// updateNest runs the nest physics for one frame
+func (ps *PlayState) updateNest() {
+	// Use the optimal frame delay to update positions
+	dt := float64(gamemanager.GGameManager.FrameDelay) / 1000
+	cfg := &ps.NestConfig
+	nest := &ps.nest
+
+	dir := nestKeyDirection()
+	if dir != 0 {
+		nest.input = nestInputKeyboard
+	}
+
+	switch {
+	case nest.input == nestInputKeyboard:
+		if dir != 0 {
+			// Turning around gets the benefit of friction, too
+			if nest.velocity*dir < 0 {
+				nest.velocity -= nest.velocity * math.Min(1, cfg.Friction*dt)
+			}
+			nest.velocity += dir * cfg.Accel * dt
+		} else {
+			nest.velocity -= nest.velocity * math.Min(1, cfg.Friction*dt)
+		}
+
+		nest.velocity = math.Max(-cfg.MaxSpeed, math.Min(cfg.MaxSpeed, nest.velocity))
+
+	case cfg.MouseMode == NestMouseFollow:
+		gap := nest.targetX - (nest.x + float64(ps.nestEntity.W)/2)
+
+		nest.velocity = gap * cfg.FollowEase
+		nest.velocity = math.Max(-cfg.FollowSpeed, math.Min(cfg.FollowSpeed, nest.velocity))
+
+		// Don't overshoot the cursor
+		if math.Abs(nest.velocity*dt) > math.Abs(gap) {
+			nest.velocity = gap / dt
+		}
+
+	default:
+		// Direct mouse mode is handled as the events come in
+		return
+	}
+
+	x := nest.x + nest.velocity*dt
+
+	// Stop dead against the walls
+	minX, maxX := ps.nestMinMaxX()
+	if x < minX {
+		x = minX
+		nest.velocity = 0
+	} else if x > maxX {
+		x = maxX
+		nest.velocity = 0
+	}
+
+	ps.positionNest(int32(math.Floor(x)) + ps.nestEntity.W/2)
+
+	// Keep the fractional part so slow speeds still move
+	nest.x = x
+}
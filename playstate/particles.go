@@ -0,0 +1,58 @@
+package playstate
+
+import (
+	"fmt"
+
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+)
+
+// particleInfo holds the particle emitters, all of which live in the
+// particle container
+type particleInfo struct {
+	shells   *scenegraph.Emitter // eggshell bits when an egg hits the ground
+	feathers *scenegraph.Emitter // when the chicken lays
+	sparkles *scenegraph.Emitter // when an egg is caught
+}
+
+// initParticles makes the emitters from the presets in the asset JSON
+func (ps *PlayState) initParticles() {
+	presets, err := scenegraph.LoadEmitterPresets(ps.assetManager, "playassets.json")
+	if err != nil {
+		panic(fmt.Sprintf("playassets.json: %v", err))
+	}
+
+	emitter := func(id string) *scenegraph.Emitter {
+		preset, ok := presets[id]
+		if !ok {
+			panic(fmt.Sprintf("playassets.json: no emitter %q in Emitters", id))
+		}
+
+		em := scenegraph.NewEmitter(preset)
+		ps.particleContainer.AddChild(em.Entity)
+
+		return em
+	}
+
+	ps.particles.shells = emitter("eggshell")
+	ps.particles.feathers = emitter("feathers")
+	ps.particles.sparkles = emitter("sparkle")
+}
+
+// resetParticles clears away all the particles
+func (ps *PlayState) resetParticles() {
+	ps.particles.shells.Reset()
+	ps.particles.feathers.Reset()
+	ps.particles.sparkles.Reset()
+}
+
+// updateParticles moves the particles along in game time
+func (ps *PlayState) updateParticles() {
+	ps.particles.shells.Update(ps.frameTime)
+	ps.particles.feathers.Update(ps.frameTime)
+	ps.particles.sparkles.Update(ps.frameTime)
+}
+
+// burstAtEntity sets off a burst from the middle of an entity
+func burstAtEntity(em *scenegraph.Emitter, e *scenegraph.Entity, y int32) {
+	em.BurstAt(float64(e.X+e.W/2), float64(y), 0)
+}
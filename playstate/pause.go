@@ -2,8 +2,9 @@ package playstate
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 
-	"github.com/beejjorgensen/eggdrop/gamecontext"
 	"github.com/beejjorgensen/eggdrop/gamemanager"
 	"github.com/beejjorgensen/eggdrop/menu"
 	"github.com/beejjorgensen/eggdrop/scenegraph"
@@ -13,34 +14,112 @@ import (
 
 // Construct the pause menu
 func (ps *PlayState) buildPauseMenu() {
+	var err error
+
 	am := ps.assetManager // asset manager
 
-	mainW := gamecontext.GContext.MainSurface.W
-	mainH := gamecontext.GContext.MainSurface.H
+	// The shade background is in the overlay layer in playgraph.json, so it
+	// covers everything else
+	ps.pauseMenuEntity = ps.rootEntity.SearchByID("pauseMenu")
+
+	shade := ps.rootEntity.SearchByID("pauseShade")
+	ps.pauseFade = scenegraph.AlphaTween(shade, 0, shade.Alpha, pauseFadeDuration)
+	ps.pauseFade.Ease = scenegraph.EaseOut
+
+	// Build pause menu
+	bindings := menu.Bindings{
+		Actions: map[string]func() bool{
+			"resume":   ps.resume,
+			"mainMenu": ps.quitToIntro,
+		},
+		Changes: map[string]func(int){
+			"mouseMode":    ps.setMouseMode,
+			"captureMouse": ps.setCaptureMouse,
+			"sensitivity":  ps.setMouseSensitivity,
+		},
+	}
 
-	// Build pause menu shade background
-	pauseBGSurface, err := util.MakeFillSurfaceAlpha(mainW, mainH, 0, 0, 0, 127)
+	ps.menu, err = menu.LoadJSON(am, "playassets.json", "pauseMenu", bindings)
 	if err != nil {
-		panic(fmt.Sprintf("Pause bgSurface: %v", err))
+		panic(fmt.Sprintf("playassets.json: %v", err))
 	}
 
-	ps.pauseMenuEntity = scenegraph.NewEntity(pauseBGSurface)
-	ps.pauseMenuEntity.Visible = false
+	ps.syncOptionsMenu()
 
-	// Build pause menu
-	mColor := ps.fontNormalColor
-	mHiColor := ps.fontHighlightColor
+	ps.pauseMenuEntity.AddChild(ps.menu.RootEntity)
+}
+
+// syncOptionsMenu makes the options menu show the current NestConfig
+func (ps *PlayState) syncOptionsMenu() {
+	config := &ps.NestConfig
 
-	menuItems := []menu.Item{
-		{AssetFontID: "menuFont", Text: "Return to Game", Color: mColor, HiColor: mHiColor},
-		{AssetFontID: "menuFont", Text: "Main Menu", Color: mColor, HiColor: mHiColor},
+	ps.menu.SetValue("mouseMode", config.MouseMode)
+
+	captured := config.MouseCapture != util.MouseCaptureNone
+	if captured {
+		ps.menu.SetValue("captureMouse", 1)
+	} else {
+		ps.menu.SetValue("captureMouse", 0)
 	}
 
-	ps.menu = menu.New(am, "playMenu", menuItems, 60, menu.MenuJustifyCenter)
+	// Sensitivity only matters while captured
+	ps.menu.SetDisabled("sensitivity", !captured)
 
-	ps.menu.RootEntity.Y = 200
+	// Pick the closest of the sensitivities offered
+	closest, best := 0, math.Inf(1)
 
-	ps.pauseMenuEntity.AddChild(ps.menu.RootEntity)
+	for i, label := range ps.menu.GetValues("sensitivity") {
+		if d := math.Abs(parseSensitivity(label) - config.MouseSensitivity); d < best {
+			closest, best = i, d
+		}
+	}
+
+	ps.menu.SetValue("sensitivity", closest)
+}
+
+// parseSensitivity reads a sensitivity from the options menu
+func parseSensitivity(label string) float64 {
+	s, err := strconv.ParseFloat(label, 64)
+	if err != nil {
+		panic(fmt.Sprintf("playassets.json: sensitivity %q: %v", label, err))
+	}
+
+	return s
+}
+
+// setMouseMode is the options menu handler for the mouse mode
+func (ps *PlayState) setMouseMode(v int) {
+	ps.NestConfig.MouseMode = v
+}
+
+// setCaptureMouse is the options menu handler for mouse capture
+func (ps *PlayState) setCaptureMouse(v int) {
+	if v != 0 {
+		ps.NestConfig.MouseCapture = util.MouseCaptureRelative
+	} else {
+		ps.NestConfig.MouseCapture = util.MouseCaptureNone
+	}
+
+	ps.menu.SetDisabled("sensitivity", v == 0)
+}
+
+// setMouseSensitivity is the options menu handler for mouse sensitivity
+func (ps *PlayState) setMouseSensitivity(v int) {
+	ps.NestConfig.MouseSensitivity = parseSensitivity(ps.menu.GetValueText("sensitivity"))
+}
+
+// resume is the menu action to get back to the game
+func (ps *PlayState) resume() bool {
+	ps.pause(false)
+
+	return false
+}
+
+// quitToIntro is the menu action to abandon the game
+func (ps *PlayState) quitToIntro() bool {
+	gamemanager.GGameManager.SetMode(gamemanager.GameModeIntro)
+
+	return false
 }
 
 // pause pauses or unpauses the game
@@ -53,17 +132,23 @@ func (ps *PlayState) pause(paused bool) {
 
 	if paused {
 		// Show pause menu
-		ps.menu.SetSelected(0)
+		ps.menu.Reset()
+		ps.pauseFade.Start()
 		ps.pauseMenuEntity.Visible = true
 
 		// Set to Event Driven
 		gm.SetEventMode(gamemanager.GameManagerEventDriven)
+
+		// Give the pointer back so the menu can be used
+		ps.captureMouse(false)
 	} else {
 		// Hide pause menu
 		ps.pauseMenuEntity.Visible = false
 
 		// Set to Poll Driven
 		gm.SetEventMode(gamemanager.GameManagerPollDriven)
+
+		ps.captureMouse(true)
 	}
 	ps.paused = paused
 }
@@ -73,10 +158,16 @@ func (ps *PlayState) handleEventPaused(event *sdl.Event) bool {
 	switch event := (*event).(type) {
 	case *sdl.KeyboardEvent:
 		//fmt.Printf("Key: %#v\n", event)
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
 		switch event.Keysym.Sym {
 
 		case sdl.K_ESCAPE:
-			ps.pause(false)
+			if !ps.menu.Back() {
+				ps.pause(false)
+			}
 
 		case sdl.K_DOWN:
 			ps.menu.SelectNext()
@@ -84,24 +175,36 @@ func (ps *PlayState) handleEventPaused(event *sdl.Event) bool {
 		case sdl.K_UP:
 			ps.menu.SelectPrev()
 
+		case sdl.K_PAGEDOWN:
+			ps.menu.SelectPageDown()
+
+		case sdl.K_PAGEUP:
+			ps.menu.SelectPageUp()
+
+		case sdl.K_LEFT:
+			ps.menu.SelectLeft()
+
+		case sdl.K_RIGHT:
+			ps.menu.SelectRight()
+
 		case sdl.K_RETURN:
-			if ps.handleMenuItem(ps.menu.GetSelected()) {
+			if ps.menu.Activate() {
 				return true // exit
 			}
 		}
 
 	case *sdl.MouseMotionEvent:
-		ps.menu.SelectByMouseY(event.Y)
+		ps.menu.SelectByMouse(event.X, event.Y)
+
+	case *sdl.MouseWheelEvent:
+		ps.menu.Scroll(int(-event.Y))
 
 	case *sdl.MouseButtonEvent:
 		if event.Type == sdl.MOUSEBUTTONDOWN {
-			ps.menu.SelectByMouseClickY(event.Y)
+			ps.menu.SelectByMouseClick(event.X, event.Y)
 
-			clicked := ps.menu.GetClicked()
-			if clicked >= 0 {
-				if ps.handleMenuItem(clicked) {
-					return true // exit
-				}
+			if ps.menu.ActivateClicked() {
+				return true // exit
 			}
 		}
 	}
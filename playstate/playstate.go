@@ -5,12 +5,14 @@ package playstate
 
 import (
 	"fmt"
+	"math/rand"
 
 	"github.com/beejjorgensen/eggdrop/gamemanager"
 	"github.com/beejjorgensen/eggdrop/menu"
 	"github.com/beejjorgensen/eggdrop/util"
 
 	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/beejjorgensen/eggdrop/collision"
 	"github.com/beejjorgensen/eggdrop/gamecontext"
 	"github.com/beejjorgensen/eggdrop/scenegraph"
 	"github.com/veandco/go-sdl2/sdl"
@@ -19,11 +21,18 @@ import (
 const (
 	stateInterludeDuration       = 250  // ms
 	stateInterludeDurationLevel1 = 1000 // ms
+	stateGameOverDuration        = 1500 // ms
+)
+
+const (
+	pauseFadeDuration = 150 // ms for the pause shade to fade in
+	hitFlashDuration  = 400 // ms for the nest to go from red back to normal
 )
 
 const (
 	stateInterlude = iota
 	stateAction
+	stateGameOver
 )
 
 type stateInfo struct {
@@ -36,12 +45,15 @@ type PlayState struct {
 	assetManager                *assetmanager.AssetManager
 	rootEntity, pauseMenuEntity *scenegraph.Entity
 
-	fontNormalColor, fontHighlightColor sdl.Color
-	bgColor                             uint32
+	fontNormalColor sdl.Color
+	bgColor         uint32
+	dirtyRects      []sdl.Rect
 
 	paused bool
 
-	menu *menu.Menu
+	menu      *menu.Menu
+	pauseFade *scenegraph.Tween
+	hitFlash  *scenegraph.Tween
 
 	nestEntity          *scenegraph.Entity
 	chixEntity          *scenegraph.Entity
@@ -49,26 +61,57 @@ type PlayState struct {
 	chixRightEntity     *scenegraph.Entity
 	interludeTextEntity *scenegraph.Entity
 	eggContainer        *scenegraph.Entity
+	splatContainer      *scenegraph.Entity
+	powerUpContainer    *scenegraph.Entity
+	particleContainer   *scenegraph.Entity
 	chixLegEntity       []*scenegraph.Entity
 
+	eggs      []*eggInfo
+	freeEggs  []*eggInfo // hidden eggs ready to launch
+	splats    []*splatInfo
+	splatClip *scenegraph.Clip
+
 	eggTimeSinceLaunch uint32
 	eggLaunchDelay     uint32
 
+	powerUps  powerUpState
+	timeScale float64 // game speed, for slow motion
+	frameTime uint32  // ms of game time that passes this frame
+	magnet    bool
+
+	world *collision.World
+
 	state stateInfo
 	level int
+	won   bool
 
-	chix chixInfo
+	seed int64
+	rng  *rand.Rand // source of all gameplay randomness
+
+	chix  chixInfo
+	nest  nestInfo
+	score scoreInfo
+	hud   hudInfo
+
+	particles particleInfo
+
+	// NestConfig tunes nest movement. Anything left empty comes from
+	// DefaultNestConfig.
+	NestConfig NestConfig
+
+	// Levels is the level table. If left nil, it's loaded from levels.json.
+	Levels *LevelFile
 }
 
 // Init initializes this gamestate
 func (ps *PlayState) Init() {
 	ps.initChix()
-	ps.initEggs()
+	ps.initNest()
+	ps.initLevels()
 
 	// Create colors
 	ps.bgColor = sdl.MapRGB(gamecontext.GContext.PixelFormat, 133, 187, 234)
 	ps.fontNormalColor = sdl.Color{R: 255, G: 255, B: 255, A: 255}
-	ps.fontHighlightColor = sdl.Color{R: 255, G: 255, B: 0, A: 255}
 
 	ps.assetManager = assetmanager.New()
 	ps.assetManager.SetOuterSurface(gamecontext.GContext.MainSurface)
@@ -96,57 +139,32 @@ func (ps *PlayState) buildScene() {
 	ps.chixLeftEntity = ps.rootEntity.SearchByID("chickenLeftContainer")
 	ps.chixRightEntity = ps.rootEntity.SearchByID("chickenRightContainer")
 	ps.chixLegEntity = []*scenegraph.Entity{
-		ps.rootEntity.SearchByID("chickenLeftLegs0"),
-		ps.rootEntity.SearchByID("chickenLeftLegs1"),
-		ps.rootEntity.SearchByID("chickenRightLegs0"),
-		ps.rootEntity.SearchByID("chickenRightLegs1"),
+		ps.rootEntity.SearchByID("chickenLeftLegs"),
+		ps.rootEntity.SearchByID("chickenRightLegs"),
 	}
 	ps.interludeTextEntity = ps.rootEntity.SearchByID("interludeText")
 	ps.eggContainer = ps.rootEntity.SearchByID("eggContainer")
+	ps.splatContainer = ps.rootEntity.SearchByID("splatContainer")
+	ps.powerUpContainer = ps.rootEntity.SearchByID("powerUpContainer")
+	ps.particleContainer = ps.rootEntity.SearchByID("particleContainer")
+
+	ps.initCollisions()
+	ps.initHUD()
+	ps.initSplats()
+	ps.initEggTypes()
+	ps.initPowerUps()
+	ps.initParticles()
 
 	// This is hackish, but we need to know the width of the chicken, and
 	// the chicken parent node is sizeless. So we copy the size from one of
 	// the children. This should probably be an option in the JSON reader.
 	ps.chixEntity.W = ps.rootEntity.SearchByID("chickenLeftImage").W
 
+	// The nest flashes red when a life is lost
+	ps.hitFlash = scenegraph.ColorModTween(ps.nestEntity, sdl.Color{R: 255, G: 60, B: 60, A: 255}, ps.nestEntity.ColorMod, hitFlashDuration)
+
 	// Pause menu stuff
 	ps.buildPauseMenu()
-	scenegraph.CenterEntityInParent(ps.menu.RootEntity, ps.rootEntity)
-	ps.rootEntity.AddChild(ps.pauseMenuEntity)
-}
-
-// handleMenuItem does the right thing with a selected menu item
-func (ps *PlayState) handleMenuItem(i int) bool {
-	switch i {
-	case 0: // Continue
-		ps.pause(false)
-	case 1: // Quit
-		// back to introstate
-		gamemanager.GGameManager.SetMode(gamemanager.GameModeIntro)
-	}
-
-	return false
-}
-
-// positionNest positions and clamps the nest
-func (ps *PlayState) positionNest(x int32) {
-	w := ps.nestEntity.W
-	x -= w / 2 // center
-
-	if x < 0 {
-		x = 0
-	}
-
-	maxX := gamecontext.GContext.WindowWidth - w
-	if x > maxX {
-		x = maxX
-	}
-
-	ps.nestEntity.MoveTo(x, ps.nestEntity.Y)
-
-	// Hackishly move the top of the nest AABB down a little to let the eggs
-	// penetrate farther before colliding
-	ps.nestEntity.MoveAABB.Y0 += 30
 }
 
 // handleEventPlaying deals with events in the play state
@@ -154,15 +172,24 @@ func (ps *PlayState) handleEventPlaying(event *sdl.Event) bool {
 	switch event := (*event).(type) {
 	case *sdl.KeyboardEvent:
 		//fmt.Printf("Key: %#v\n", event)
+
+		// Only key presses, so the release of the key that unpaused doesn't
+		// pause again
+		if event.Type != sdl.KEYDOWN {
+			break
+		}
+
 		switch event.Keysym.Sym {
 
 		case sdl.K_ESCAPE, sdl.K_p:
-			ps.pause(true)
+			if ps.state.state != stateGameOver {
+				ps.pause(true)
+			}
 		}
 
 	case *sdl.MouseMotionEvent:
 		if ps.state.state == stateAction {
-			ps.positionNest(event.X)
+			ps.nestMouseMoved(event)
 		}
 	}
 
@@ -196,6 +223,14 @@ func (ps *PlayState) updateState() {
 			ps.setState(stateAction)
 		}
 	case stateAction:
+	case stateGameOver:
+		if diff >= stateGameOverDuration {
+			if ps.madeHighScore() {
+				gamemanager.GGameManager.SetMode(gamemanager.GameModeNameEntry)
+			} else {
+				gamemanager.GGameManager.SetMode(gamemanager.GameModeGameOver)
+			}
+		}
 	}
 
 }
@@ -205,14 +240,32 @@ func (ps *PlayState) updateState() {
 func (ps *PlayState) update() {
 	ps.updateState()
 
+	if ps.paused {
+		return
+	}
+
+	ps.frameTime = uint32(float64(gamemanager.GGameManager.FrameDelay)*ps.timeScale + 0.5)
+
+	ps.hitFlash.Update()
+	ps.updateParticles()
+
 	switch ps.state.state {
 	case stateAction:
-		if !ps.paused {
-			ps.updateChix()
-			ps.updateEggs()
+		ps.updateChix()
+		ps.updateEggs()
+		ps.updatePowerUps()
+		ps.updateNest()
+		ps.updateSplats()
 
-			ps.testEggCollision()
-		}
+		// Bring the collision bounds up to date with all that moving
+		ps.rootEntity.UpdateTransforms()
+
+		ps.world.Step()
+		ps.checkLevelComplete()
+
+	case stateGameOver:
+		// Let the last splat play out
+		ps.updateSplats()
 	}
 }
 
@@ -220,11 +273,21 @@ func (ps *PlayState) update() {
 func (ps *PlayState) Render(mainWindowSurface *sdl.Surface) {
 	ps.update()
 
-	mainWindowSurface.FillRect(nil, ps.bgColor)
-
 	ps.interludeTextEntity.Visible = ps.state.state == stateInterlude
 
-	ps.rootEntity.Render(mainWindowSurface)
+	ps.updateHUD()
+
+	if ps.paused {
+		ps.pauseFade.Update()
+		ps.menu.Update()
+	}
+
+	ps.dirtyRects = ps.rootEntity.RenderDirty(mainWindowSurface, ps.bgColor)
+}
+
+// DirtyRects returns the parts of the window changed by the last Render
+func (ps *PlayState) DirtyRects() []sdl.Rect {
+	return ps.dirtyRects
 }
 
 // constructInterludeImage builds the "LEVEL X" image
@@ -244,15 +307,27 @@ func (ps *PlayState) constructInterludeImage() {
 func (ps *PlayState) setState(state int) {
 	ps.state.state = state
 	ps.state.startTime = sdl.GetTicks()
+
+	if state == stateGameOver {
+		ps.resetPowerUps()
+	}
 }
 
 // WillShow is called just before this state begins
 func (ps *PlayState) WillShow() {
+	ps.resetRandom()
 	ps.resetChix()
+	ps.resetEggs()
+	ps.resetSplats()
+	ps.resetParticles()
+	ps.resetNest()
+	ps.resetScore()
 	ps.pause(false)
-	ps.level = 1
-	ps.setState(stateInterlude)
-	ps.constructInterludeImage()
+	ps.won = false
+	ps.startLevel(1)
+
+	// Some other state has been drawing on the window
+	ps.rootEntity.Invalidate()
 
 	// call this to move on to the next transition state
 	gamemanager.GGameManager.WillShowComplete()
@@ -260,11 +335,15 @@ func (ps *PlayState) WillShow() {
 
 // WillHide is called just before this state ends
 func (ps *PlayState) WillHide() {
+	ps.captureMouse(false)
+	ps.resetPowerUps()
+	ps.publishResult()
 }
 
 // DidShow is called just after this statebegins
 func (ps *PlayState) DidShow() {
 	gamemanager.GGameManager.SetEventMode(gamemanager.GameManagerPollDriven)
+	ps.captureMouse(true)
 }
 
 // DidHide is called just after this state ends
@@ -0,0 +1,259 @@
+package playstate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/beejjorgensen/eggdrop/collision"
+	"github.com/beejjorgensen/eggdrop/gamemanager"
+	"github.com/beejjorgensen/eggdrop/scenegraph"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	powerUpFallSpeedMult = 0.6 // fraction of the level's egg fall speed
+
+	slowMotionTimeScale = 0.5 // game time runs this fast in slow motion
+
+	magnetRange = 300 // px, horizontal distance the magnet reaches
+	magnetMinY  = 200 // px, eggs above this are too far up to feel it
+	magnetSpeed = 250 // px/s, how fast eggs get dragged sideways
+)
+
+// Power-up kinds, in HUD display order
+const (
+	powerUpWide = iota
+	powerUpSlow
+	powerUpMagnet
+	powerUpLife
+	powerUpCount
+)
+
+// powerUpKind describes one kind of power-up. Duration 0 means it takes effect
+// immediately and there's nothing to expire.
+type powerUpKind struct {
+	name     string // for the HUD
+	asset    string
+	duration uint32 // ms
+}
+
+var powerUpKinds = [powerUpCount]powerUpKind{
+	powerUpWide:   {name: "WIDE", asset: "powerWideImage", duration: 10000},
+	powerUpSlow:   {name: "SLOW", asset: "powerSlowImage", duration: 6000},
+	powerUpMagnet: {name: "MAGNET", asset: "powerMagnetImage", duration: 8000},
+	powerUpLife:   {name: "1UP", asset: "powerLifeImage"},
+}
+
+// startPowerUp turns on the effect of a power-up
+func (ps *PlayState) startPowerUp(i int) {
+	switch i {
+	case powerUpWide:
+		ps.setNestSurface("nestWideImage")
+	case powerUpSlow:
+		ps.timeScale = slowMotionTimeScale
+	case powerUpMagnet:
+		ps.magnet = true
+	case powerUpLife:
+		ps.addLives(1)
+	}
+}
+
+// endPowerUp turns off the effect of a timed power-up
+func (ps *PlayState) endPowerUp(i int) {
+	switch i {
+	case powerUpWide:
+		ps.setNestSurface("nestImage")
+	case powerUpSlow:
+		ps.timeScale = 1
+	case powerUpMagnet:
+		ps.magnet = false
+	}
+}
+
+// powerUpInfo is a falling power-up pickup
+type powerUpInfo struct {
+	entity *scenegraph.Entity
+	kind   int
+}
+
+// powerUpState holds the falling pickups and the active timers. The timers run
+// on game time, so they hold still while the game is paused.
+type powerUpState struct {
+	pickups   []*powerUpInfo
+	remaining [powerUpCount]uint32 // ms left on each active power-up
+	surfaces  [powerUpCount]*sdl.Surface
+}
+
+// initPowerUps looks up the power-up images
+func (ps *PlayState) initPowerUps() {
+	for i, kind := range powerUpKinds {
+		ps.powerUps.surfaces[i] = ps.assetManager.Surfaces[kind.asset]
+	}
+
+	ps.timeScale = 1
+}
+
+// resetPowerUps hides all pickups and ends everything that's active
+func (ps *PlayState) resetPowerUps() {
+	for _, p := range ps.powerUps.pickups {
+		p.entity.Visible = false
+	}
+
+	for i := range ps.powerUps.remaining {
+		if ps.powerUps.remaining[i] > 0 {
+			ps.powerUps.remaining[i] = 0
+			ps.endPowerUp(i)
+		}
+	}
+}
+
+// setNestSurface swaps the nest image, keeping it centered where it was
+func (ps *PlayState) setNestSurface(assetID string) {
+	center := ps.nestEntity.X + ps.nestEntity.W/2
+
+	ps.nestEntity.Surface = ps.assetManager.Surfaces[assetID]
+	ps.nestEntity.W = ps.nestEntity.Surface.W
+	ps.nestEntity.H = ps.nestEntity.Surface.H
+
+	ps.positionNest(center)
+}
+
+// getPowerUp returns an unused pickup, creating it if necessary
+func (ps *PlayState) getPowerUp() *powerUpInfo {
+	for _, p := range ps.powerUps.pickups {
+		if !p.entity.Visible {
+			return p
+		}
+	}
+
+	p := &powerUpInfo{entity: scenegraph.NewEntity(nil)}
+	ps.powerUpContainer.AddChild(p.entity)
+	ps.powerUps.pickups = append(ps.powerUps.pickups, p)
+
+	ps.world.Add(p.entity, layerPowerUp, layerNest, collision.Handlers{
+		Enter: func(self, other collision.Body) {
+			p.entity.Visible = false
+			ps.activatePowerUp(p.kind)
+		},
+	})
+
+	return p
+}
+
+// maybeLaunchPowerUp drops a random power-up next to a new egg, if the level's
+// odds say so
+func (ps *PlayState) maybeLaunchPowerUp(x int32) {
+	if ps.rng.Float64() >= ps.levelInfo().PowerUpChance {
+		return
+	}
+
+	p := ps.getPowerUp()
+	e := p.entity
+
+	p.kind = ps.rng.Intn(powerUpCount)
+
+	e.Surface = ps.powerUps.surfaces[p.kind]
+	e.W = e.Surface.W
+	e.H = e.Surface.H
+	e.X = x
+	e.Y = eggStartingY
+	e.ResetSweep()
+	e.Visible = true
+}
+
+// activatePowerUp starts a power-up, or extends it if it's already going
+func (ps *PlayState) activatePowerUp(i int) {
+	duration := powerUpKinds[i].duration
+
+	if duration == 0 {
+		ps.startPowerUp(i)
+		return
+	}
+
+	if ps.powerUps.remaining[i] == 0 {
+		ps.startPowerUp(i)
+	}
+
+	ps.powerUps.remaining[i] = duration
+}
+
+// updatePowerUps drops the pickups, catches them, and runs down the timers
+func (ps *PlayState) updatePowerUps() {
+	// Timers run on real frame time so slow motion doesn't stretch itself out
+	frameDelay := gamemanager.GGameManager.FrameDelay
+
+	for i := range ps.powerUps.remaining {
+		remaining := &ps.powerUps.remaining[i]
+
+		if *remaining == 0 {
+			continue
+		}
+
+		if *remaining <= frameDelay {
+			*remaining = 0
+			ps.endPowerUp(i)
+		} else {
+			*remaining -= frameDelay
+		}
+	}
+
+	speed := float64(ps.levelInfo().FallSpeed) * powerUpFallSpeedMult
+	dY := int32(speed * float64(ps.frameTime) / 1000)
+
+	for _, p := range ps.powerUps.pickups {
+		e := p.entity
+
+		if !e.Visible {
+			continue
+		}
+
+		e.MoveTo(e.X, e.Y+dY)
+
+		if e.Y > eggSplatY {
+			// Missed it. No harm done.
+			e.Visible = false
+		}
+	}
+}
+
+// magnetPull returns how far the magnet drags an egg toward the nest this
+// frame. Only harmless eggs feel it.
+func (ps *PlayState) magnetPull(egg *eggInfo) int32 {
+	if !ps.magnet || egg.kind.CatchPoints < 0 || egg.kind.CatchLives < 0 {
+		return 0
+	}
+
+	e := egg.entity
+
+	if e.Y < magnetMinY {
+		return 0
+	}
+
+	gap := float64((ps.nestEntity.X + ps.nestEntity.W/2) - (e.X + e.W/2))
+	if math.Abs(gap) > magnetRange {
+		return 0
+	}
+
+	step := magnetSpeed * float64(ps.frameTime) / 1000
+	if step > math.Abs(gap) {
+		step = math.Abs(gap)
+	}
+
+	return int32(math.Copysign(step, gap))
+}
+
+// powerUpHUDText describes the active power-ups and their time left
+func (ps *PlayState) powerUpHUDText() string {
+	var parts []string
+
+	for i, remaining := range ps.powerUps.remaining {
+		if remaining > 0 {
+			// Round up so it never shows 0 while still active
+			seconds := (remaining + 999) / 1000
+			parts = append(parts, fmt.Sprintf("%s %d", powerUpKinds[i].name, seconds))
+		}
+	}
+
+	return strings.Join(parts, "  ")
+}
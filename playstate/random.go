@@ -0,0 +1,45 @@
+package playstate
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+)
+
+// Seeds are kept to this many digits or fewer so they're easy to share
+const maxSeed = 1000000000
+
+// DailySeed derives the daily challenge seed from the date. The date is taken
+// in UTC so everyone gets the same seed on the same day.
+func DailySeed(t time.Time) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "eggdrop-daily-%s", t.UTC().Format("2006-01-02"))
+
+	return int64(h.Sum64()%(maxSeed-1)) + 1
+}
+
+// RandomSeed picks a fresh seed when the player didn't ask for one
+func RandomSeed() int64 {
+	return rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(maxSeed-1) + 1
+}
+
+// resetRandom sets up the gameplay random number generator for a new game.
+// All gameplay randomness must come from ps.rng so that a seed always
+// produces the same game.
+func (ps *PlayState) resetRandom() {
+	gc := gamecontext.GContext
+
+	switch {
+	case gc.Daily:
+		ps.seed = DailySeed(time.Now())
+	case gc.Seed != 0:
+		ps.seed = gc.Seed
+	default:
+		ps.seed = RandomSeed()
+	}
+
+	ps.rng = rand.New(rand.NewSource(ps.seed))
+}
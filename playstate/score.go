@@ -0,0 +1,121 @@
+package playstate
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/beejjorgensen/eggdrop/gamecontext"
+	"github.com/beejjorgensen/eggdrop/highscore"
+)
+
+const (
+	eggPointsLevelBonus = 5 // extra points per egg for each level past the first
+
+	startingLives = 3
+	maxLives      = 9
+)
+
+// scoreInfo holds the player's score and lives
+type scoreInfo struct {
+	score       int
+	caught      int // total eggs caught this game
+	levelCaught int // eggs caught this level
+	lives       int
+}
+
+// resetScore starts a new game's score
+func (ps *PlayState) resetScore() {
+	ps.score = scoreInfo{lives: startingLives}
+}
+
+// addPoints changes the score, not letting it go negative
+func (ps *PlayState) addPoints(points int) {
+	ps.score.score += points
+
+	if ps.score.score < 0 {
+		ps.score.score = 0
+	}
+}
+
+// addLives gives or takes away lives
+func (ps *PlayState) addLives(lives int) {
+	for ; lives < 0; lives++ {
+		ps.loseLife()
+	}
+
+	ps.score.lives += lives
+	if ps.score.lives > maxLives {
+		ps.score.lives = maxLives
+	}
+}
+
+// scoreCatch applies the outcome of catching an egg
+func (ps *PlayState) scoreCatch(kind *EggType) {
+	points := kind.CatchPoints
+	if points > 0 {
+		points += (ps.level - 1) * eggPointsLevelBonus
+	}
+	ps.addPoints(points)
+
+	if kind.Quota {
+		ps.score.caught++
+		ps.score.levelCaught++
+	}
+
+	ps.addLives(kind.CatchLives)
+}
+
+// scoreSplat applies the outcome of an egg hitting the ground
+func (ps *PlayState) scoreSplat(kind *EggType) {
+	ps.addPoints(kind.SplatPoints)
+	ps.addLives(kind.SplatLives)
+}
+
+// loseLife takes a life away, ending the game if there are none left
+func (ps *PlayState) loseLife() {
+	if ps.score.lives == 0 {
+		return
+	}
+
+	ps.score.lives--
+	ps.hitFlash.Start()
+
+	if ps.score.lives == 0 {
+		ps.setState(stateGameOver)
+	}
+}
+
+// eggsRemaining returns how many more eggs are needed to clear this level
+func (ps *PlayState) eggsRemaining() int {
+	remaining := ps.levelInfo().Quota - ps.score.levelCaught
+
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// publishResult makes the game outcome available to the next mode
+func (ps *PlayState) publishResult() {
+	gamecontext.GContext.LastGame = gamecontext.GameResult{
+		Score:  ps.score.score,
+		Level:  ps.level,
+		Caught: ps.score.caught,
+		Won:    ps.won,
+		Seed:   ps.seed,
+		Daily:  gamecontext.GContext.Daily,
+	}
+}
+
+// madeHighScore reports whether this game's score belongs in the high score
+// table
+func (ps *PlayState) madeHighScore() bool {
+	table, err := highscore.LoadDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "high scores: %v\n", err)
+		return false
+	}
+
+	return table.Qualifies(ps.score.score)
+}
@@ -0,0 +1,75 @@
+package playstate
+
+import "github.com/beejjorgensen/eggdrop/scenegraph"
+
+const (
+	splatY = 536 // pixels, top of the splat sprite
+
+	splatFrameDuration = 70  // ms per frame of the splat animation
+	splatLinger        = 700 // ms the last frame stays on the ground
+)
+
+// splatInfo tracks one splat on the ground
+type splatInfo struct {
+	entity *scenegraph.Entity
+}
+
+// initSplats builds the splat animation, which hides the splat when it's done
+func (ps *PlayState) initSplats() {
+	am := ps.assetManager
+
+	ps.splatClip = &scenegraph.Clip{
+		Name: "splat",
+		Mode: scenegraph.AnimOnce,
+		Frames: []scenegraph.Frame{
+			{Surface: am.Surfaces["splat0Image"], Duration: splatFrameDuration},
+			{Surface: am.Surfaces["splat1Image"], Duration: splatFrameDuration},
+			{Surface: am.Surfaces["splat2Image"], Duration: splatLinger},
+		},
+		OnComplete: func(e *scenegraph.Entity) {
+			e.Visible = false
+		},
+	}
+}
+
+// resetSplats hides all the splats
+func (ps *PlayState) resetSplats() {
+	for _, splat := range ps.splats {
+		splat.entity.Visible = false
+	}
+}
+
+// getSplat returns an unused splat, creating it if necessary
+func (ps *PlayState) getSplat() *splatInfo {
+	for _, splat := range ps.splats {
+		if !splat.entity.Visible {
+			return splat
+		}
+	}
+
+	splat := &splatInfo{entity: scenegraph.NewEntity(nil)}
+	scenegraph.NewAnimation(splat.entity).AddClip(ps.splatClip)
+
+	ps.splatContainer.AddChild(splat.entity)
+	ps.splats = append(ps.splats, splat)
+
+	return splat
+}
+
+// splatEgg starts a splat where the given egg hit the ground
+func (ps *PlayState) splatEgg(egg *scenegraph.Entity) {
+	splat := ps.getSplat()
+	e := splat.entity
+
+	e.Anim.Start("splat")
+	e.X = egg.X + (egg.W-e.W)/2
+	e.Y = splatY
+	e.Visible = true
+
+	burstAtEntity(ps.particles.shells, egg, splatY)
+}
+
+// updateSplats animates the splats, which clear themselves away when done
+func (ps *PlayState) updateSplats() {
+	ps.splatContainer.Animate(ps.frameTime)
+}
@@ -0,0 +1,414 @@
+package scenegraph
+
+import (
+	"fmt"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Animation modes for Clip.Mode
+const (
+	AnimLoop     = iota // start over after the last frame
+	AnimPingPong        // run backward after the last frame, then forward again
+	AnimOnce            // stop on the last frame
+)
+
+// animModes are the animation modes as they appear in JSON
+var animModes = map[string]int{
+	"LOOP":     AnimLoop,
+	"PINGPONG": AnimPingPong,
+	"ONCE":     AnimOnce,
+}
+
+// Frame is a single cel of an animation. The offset moves the entity from
+// where it would otherwise be, for frames that don't line up with each other.
+type Frame struct {
+	Surface          *sdl.Surface
+	Duration         uint32 // ms
+	OffsetX, OffsetY int32
+}
+
+// Clip is a named sequence of frames. Clips can be shared between entities.
+type Clip struct {
+	Name   string
+	Frames []Frame
+	Mode   int
+
+	// OnComplete is called when a one-shot clip finishes, or each time a
+	// looping or ping-pong clip gets back to the start
+	OnComplete func(e *Entity)
+}
+
+// Animation plays clips on an entity by swapping its surface
+type Animation struct {
+	Clips map[string]*Clip
+
+	entity  *Entity
+	clip    *Clip
+	frame   int
+	dir     int    // 1 forward, -1 backward for ping-pong
+	elapsed uint32 // ms into the current frame
+	playing bool
+
+	offX, offY int32 // frame offset currently applied to the entity
+}
+
+// NewAnimation gives an entity an animation with no clips
+func NewAnimation(e *Entity) *Animation {
+	a := &Animation{
+		Clips:  make(map[string]*Clip),
+		entity: e,
+		dir:    1,
+	}
+
+	e.Anim = a
+
+	return a
+}
+
+// AddClip adds a clip to the animation, replacing any with the same name
+func (a *Animation) AddClip(clip *Clip) {
+	a.Clips[clip.Name] = clip
+}
+
+// Play starts a clip from the beginning. If it's already playing, it carries
+// on from where it is.
+func (a *Animation) Play(name string) {
+	if a.playing && a.clip != nil && a.clip.Name == name {
+		return
+	}
+
+	a.Start(name)
+}
+
+// Start starts a clip from the beginning, even if it's already playing
+func (a *Animation) Start(name string) {
+	clip, ok := a.Clips[name]
+	if !ok {
+		panic(fmt.Sprintf("scenegraph: %s: no animation clip %q", a.entity.ID, name))
+	}
+
+	a.clip = clip
+	a.dir = 1
+	a.playing = true
+	a.SetFrame(0)
+}
+
+// Stop freezes the animation on the current frame
+func (a *Animation) Stop() {
+	a.playing = false
+}
+
+// Playing reports whether the animation is running
+func (a *Animation) Playing() bool {
+	return a.playing
+}
+
+// ClipName returns the name of the current clip, or "" if there isn't one
+func (a *Animation) ClipName() string {
+	if a.clip == nil {
+		return ""
+	}
+
+	return a.clip.Name
+}
+
+// Frame returns the index of the current frame
+func (a *Animation) Frame() int {
+	return a.frame
+}
+
+// SetFrame jumps to a frame of the current clip
+func (a *Animation) SetFrame(index int) {
+	f := &a.clip.Frames[index]
+	e := a.entity
+
+	a.frame = index
+	a.elapsed = 0
+
+	e.Surface = f.Surface
+	e.W = f.Surface.W
+	e.H = f.Surface.H
+
+	e.X += f.OffsetX - a.offX
+	e.Y += f.OffsetY - a.offY
+	a.offX = f.OffsetX
+	a.offY = f.OffsetY
+}
+
+// complete tells anyone who's interested that the clip's done a full run
+func (a *Animation) complete() {
+	if a.clip.OnComplete != nil {
+		a.clip.OnComplete(a.entity)
+	}
+}
+
+// Step moves to the next frame according to the clip's mode, regardless of
+// the frame durations. This is handy for animations driven by something
+// other than time, like distance walked.
+func (a *Animation) Step() {
+	if a.clip == nil {
+		return
+	}
+
+	last := len(a.clip.Frames) - 1
+	next := a.frame + a.dir
+
+	switch a.clip.Mode {
+	case AnimLoop:
+		if next > last {
+			next = 0
+			a.SetFrame(next)
+			a.complete()
+			return
+		}
+
+	case AnimPingPong:
+		if next > last || next < 0 {
+			a.dir = -a.dir
+			next = a.frame + a.dir
+
+			if next < 0 || next > last {
+				next = a.frame // only one frame
+			}
+		}
+
+		a.SetFrame(next)
+
+		if next == 0 && a.dir == -1 {
+			a.dir = 1
+			a.complete()
+		}
+		return
+
+	case AnimOnce:
+		if next > last {
+			a.playing = false
+			a.complete()
+			return
+		}
+	}
+
+	a.SetFrame(next)
+}
+
+// Update runs the animation forward by dt ms
+func (a *Animation) Update(dt uint32) {
+	if !a.playing || a.clip == nil {
+		return
+	}
+
+	a.elapsed += dt
+
+	// Might need to skip frames if dt was long
+	for a.playing {
+		duration := a.clip.Frames[a.frame].Duration
+		if duration == 0 || a.elapsed < duration {
+			break
+		}
+
+		elapsed := a.elapsed - duration
+		a.Step()
+		a.elapsed = elapsed
+	}
+}
+
+// Animate updates the animations of all the visible entities in a hierarchy
+func (e *Entity) Animate(dt uint32) {
+	if !e.Visible {
+		return
+	}
+
+	if e.Anim != nil {
+		e.Anim.Update(dt)
+	}
+
+	for _, c := range e.Children {
+		c.Animate(dt)
+	}
+}
+
+// AtlasFrame copies a rectangle out of a larger surface to make a frame. The
+// copy has per-pixel alpha; any color key is turned into transparency.
+func AtlasFrame(atlas *sdl.Surface, rect sdl.Rect) (*sdl.Surface, error) {
+	rgba, err := atlas.ConvertFormat(sdl.PIXELFORMAT_RGBA8888, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer rgba.Free()
+
+	pf := rgba.Format
+
+	frame, err := sdl.CreateRGBSurface(0, rect.W, rect.H, 32, pf.Rmask, pf.Gmask, pf.Bmask, pf.Amask)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the pixels straight over, alpha and all
+	rgba.SetBlendMode(sdl.BLENDMODE_NONE)
+	rgba.Blit(&rect, frame, nil)
+	frame.SetBlendMode(sdl.BLENDMODE_BLEND)
+
+	return frame, nil
+}
+
+// jsonInt32 gets a whole number out of a JSON property
+func jsonInt32(filename, id, key string, v interface{}) int32 {
+	return int32(jsonFloat(filename, id, key, v))
+}
+
+// jsonRect gets an [X,Y,W,H] array out of a JSON property
+func jsonRect(filename, id, key string, v interface{}) sdl.Rect {
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 4 {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: %s must be [X,Y,W,H]", filename, id, key))
+	}
+
+	return sdl.Rect{
+		X: jsonInt32(filename, id, key, a[0]),
+		Y: jsonInt32(filename, id, key, a[1]),
+		W: jsonInt32(filename, id, key, a[2]),
+		H: jsonInt32(filename, id, key, a[3]),
+	}
+}
+
+// atlasFrame makes a frame from an asset, panicking if it can't
+func atlasFrame(am *assetmanager.AssetManager, filename, id, asset string, rect sdl.Rect) *sdl.Surface {
+	atlas, ok := am.Surfaces[asset]
+	if !ok {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unknown Atlas %q", filename, id, asset))
+	}
+
+	surface, err := AtlasFrame(atlas, rect)
+	if err != nil {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: atlas frame: %v", filename, id, err))
+	}
+
+	return surface
+}
+
+// loadJSONFrame reads a frame. It can be a whole asset, or a Rect out of an
+// Atlas asset. The duration defaults to the clip's.
+func loadJSONFrame(am *assetmanager.AssetManager, node map[string]interface{}, filename, id string, duration uint32) Frame {
+	f := Frame{Duration: duration}
+
+	for k, v := range node {
+		switch k {
+		case "Asset":
+			surface, ok := am.Surfaces[v.(string)]
+			if !ok {
+				panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unknown Asset %q", filename, id, v))
+			}
+			f.Surface = surface
+		case "Atlas":
+			f.Surface = atlasFrame(am, filename, id, v.(string), jsonRect(filename, id, "Rect", node["Rect"]))
+		case "Rect":
+			// handled with Atlas
+		case "Duration":
+			f.Duration = uint32(jsonFloat(filename, id, k, v))
+		case "X":
+			f.OffsetX = jsonInt32(filename, id, k, v)
+		case "Y":
+			f.OffsetY = jsonInt32(filename, id, k, v)
+		default:
+			panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unrecognized frame key: %s", filename, id, k))
+		}
+	}
+
+	if f.Surface == nil {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: frame needs an Asset or Atlas", filename, id))
+	}
+
+	return f
+}
+
+// loadJSONClip reads a clip. Frames can be listed one by one, or cut in a row
+// from an Atlas asset with FrameW, FrameH and Count.
+func loadJSONClip(am *assetmanager.AssetManager, node map[string]interface{}, filename, id string) *Clip {
+	clip := &Clip{}
+
+	name, ok := node["Name"].(string)
+	if !ok {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: clip missing Name", filename, id))
+	}
+	clip.Name = name
+
+	var duration uint32
+	if v, ok := node["Duration"]; ok {
+		duration = uint32(jsonFloat(filename, id, "Duration", v))
+	}
+
+	for k, v := range node {
+		switch k {
+		case "Name", "Duration", "FrameW", "FrameH", "Count":
+			// handled above or with Atlas
+		case "Mode":
+			mode, ok := animModes[v.(string)]
+			if !ok {
+				panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unknown Mode %q", filename, id, v))
+			}
+			clip.Mode = mode
+		case "Frames":
+			for _, f := range v.([]interface{}) {
+				clip.Frames = append(clip.Frames, loadJSONFrame(am, f.(map[string]interface{}), filename, id, duration))
+			}
+		case "Atlas":
+			w := jsonInt32(filename, id, "FrameW", node["FrameW"])
+			h := jsonInt32(filename, id, "FrameH", node["FrameH"])
+			count := int(jsonFloat(filename, id, "Count", node["Count"]))
+
+			for i := 0; i < count; i++ {
+				rect := sdl.Rect{X: int32(i) * w, Y: 0, W: w, H: h}
+				clip.Frames = append(clip.Frames, Frame{
+					Surface:  atlasFrame(am, filename, id, v.(string), rect),
+					Duration: duration,
+				})
+			}
+		default:
+			panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unrecognized clip key: %s", filename, id, k))
+		}
+	}
+
+	if len(clip.Frames) == 0 {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: clip %q has no Frames", filename, id, name))
+	}
+
+	return clip
+}
+
+// loadJSONAnimation reads the Animation property of an entity. Play names a
+// clip to start; otherwise the entity shows the first frame of the first
+// clip.
+func loadJSONAnimation(am *assetmanager.AssetManager, entity *Entity, node map[string]interface{}, filename, id string) {
+	a := NewAnimation(entity)
+
+	clips, ok := node["Clips"].([]interface{})
+	if !ok || len(clips) == 0 {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: Animation needs Clips", filename, id))
+	}
+
+	var first string
+
+	for _, c := range clips {
+		clip := loadJSONClip(am, c.(map[string]interface{}), filename, id)
+		a.AddClip(clip)
+
+		if first == "" {
+			first = clip.Name
+		}
+	}
+
+	for k := range node {
+		if k != "Clips" && k != "Play" {
+			panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unrecognized Animation key: %s", filename, id, k))
+		}
+	}
+
+	if play, ok := node["Play"].(string); ok {
+		a.Start(play)
+	} else {
+		a.Start(first)
+		a.Stop()
+	}
+}
@@ -0,0 +1,104 @@
+package scenegraph
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Blend modes for Entity.Blend
+const (
+	BlendDefault = iota // inherit, or use the surface's own
+	BlendNone
+	BlendAlpha
+	BlendAdd
+	BlendMod
+)
+
+// blendModes maps the Blend constants to SDL
+var blendModes = [...]sdl.BlendMode{
+	BlendNone:  sdl.BLENDMODE_NONE,
+	BlendAlpha: sdl.BLENDMODE_BLEND,
+	BlendAdd:   sdl.BLENDMODE_ADD,
+	BlendMod:   sdl.BLENDMODE_MOD,
+}
+
+// blendNames are the Blend constants as they appear in JSON
+var blendNames = map[string]int{
+	"DEFAULT": BlendDefault,
+	"NONE":    BlendNone,
+	"ALPHA":   BlendAlpha,
+	"ADD":     BlendAdd,
+	"MOD":     BlendMod,
+}
+
+// look is the accumulated Alpha, ColorMod and Blend of an entity
+type look struct {
+	alpha   uint8
+	r, g, b uint8
+	blend   int
+}
+
+// plainLook changes nothing about how a surface is drawn
+var plainLook = look{alpha: 255, r: 255, g: 255, b: 255, blend: BlendDefault}
+
+// mul8 multiplies two 0-255 values as if they were 0-1
+func mul8(a, b uint8) uint8 {
+	return uint8((uint32(a)*uint32(b) + 127) / 255)
+}
+
+// inheritLook combines the entity's Alpha, ColorMod and Blend with its
+// parent's. The parent is nil for the root.
+func (e *Entity) inheritLook(parent *Entity) {
+	p := plainLook
+	if parent != nil {
+		p = parent.look
+	}
+
+	e.look = look{
+		alpha: mul8(p.alpha, e.Alpha),
+		r:     mul8(p.r, e.ColorMod.R),
+		g:     mul8(p.g, e.ColorMod.G),
+		b:     mul8(p.b, e.ColorMod.B),
+		blend: p.blend,
+	}
+
+	if e.Blend != BlendDefault {
+		e.look.blend = e.Blend
+	}
+}
+
+// blit draws a surface for the entity with its look applied. The surface is
+// either the entity's own or a transformed copy of it; either way, the
+// entity's surface's own alpha and color mods are kept and multiplied in.
+// Surfaces can be shared between entities, so the entity's surface is put
+// back the way it was afterward.
+func (e *Entity) blit(surface, dest *sdl.Surface, rect *sdl.Rect) {
+	own := surface == e.Surface
+
+	if own && e.look == plainLook {
+		surface.Blit(nil, dest, rect)
+		return
+	}
+
+	alpha, _ := e.Surface.GetAlphaMod()
+	r, g, b, _ := e.Surface.GetColorMod()
+	ownBlend, _ := e.Surface.GetBlendMode()
+
+	blend := ownBlend
+	if !own {
+		// Transformed copies have transparent corners that need blending
+		blend = sdl.BLENDMODE_BLEND
+	}
+	if e.look.blend != BlendDefault {
+		blend = blendModes[e.look.blend]
+	}
+
+	surface.SetAlphaMod(mul8(alpha, e.look.alpha))
+	surface.SetColorMod(mul8(r, e.look.r), mul8(g, e.look.g), mul8(b, e.look.b))
+	surface.SetBlendMode(blend)
+
+	surface.Blit(nil, dest, rect)
+
+	if own {
+		surface.SetAlphaMod(alpha)
+		surface.SetColorMod(r, g, b)
+		surface.SetBlendMode(ownBlend)
+	}
+}
@@ -0,0 +1,131 @@
+package scenegraph
+
+import (
+	"fmt"
+
+	"github.com/beejjorgensen/eggdrop/aabb"
+)
+
+// Collider shapes for Collider.Shape
+const (
+	ColliderBox  = iota // the entity's box, less the insets
+	ColliderNone        // doesn't collide with anything
+)
+
+// Collider is the part of an entity that counts for collisions, in entity
+// coordinates. The insets pull the edges of the entity's W by H box in, so
+// e.g. a nest can let eggs sink into it a bit before they're caught.
+type Collider struct {
+	Shape                    int
+	Left, Top, Right, Bottom int32
+}
+
+// transformPass counts calls to UpdateTransforms, so entities can tell if
+// they were left out of the last one
+var transformPass uint32
+
+// UpdateTransforms works out the world transforms and bounds of every visible
+// entity in the hierarchy, without drawing anything. Call it once a frame
+// after moving things and before testing for collisions.
+//
+// Each entity's WorldAABB is its collider in world coordinates, and its
+// SweptAABB also covers where the collider was on the last pass, so fast
+// movers can't skip through things between frames. Entities that weren't in
+// the last pass (e.g. because they were hidden) don't sweep.
+func (e *Entity) UpdateTransforms() {
+	transformPass++
+
+	e.updateTransforms(nil)
+}
+
+// updateTransforms is the recursive part of UpdateTransforms
+func (e *Entity) updateTransforms(parent *Entity) {
+	if !e.Visible {
+		return
+	}
+
+	if parent != nil {
+		e.EntityToWorld = parent.EntityToWorld.Mul(e.localMatrix())
+	} else {
+		e.EntityToWorld = e.localMatrix()
+	}
+	e.WorldToEntity = e.EntityToWorld.Inverse()
+
+	c := &e.Collider
+	x0, y0, x1, y1 := e.EntityToWorld.BoundsRect(c.Left, c.Top, e.W-c.Right, e.H-c.Bottom)
+	box := aabb.AABB{X0: x0, Y0: y0, X1: x1, Y1: y1}
+
+	if e.aabbPass != 0 && e.aabbPass == transformPass-1 {
+		e.SweptAABB = e.WorldAABB.Union(&box)
+	} else {
+		e.SweptAABB = box
+	}
+
+	e.WorldAABB = box
+	e.aabbPass = transformPass
+
+	for _, child := range e.Children {
+		child.updateTransforms(e)
+	}
+}
+
+// ResetSweep makes the entity's next swept box start from wherever it is
+// then. Call it after teleporting an entity, e.g. when reusing a pooled one,
+// so it doesn't sweep across everything between the old and new spots.
+func (e *Entity) ResetSweep() {
+	e.aabbPass = 0
+}
+
+// CollisionActive reports whether the entity can collide with anything right
+// now. Hidden entities, ones without colliders, and ones left out of the last
+// UpdateTransforms (which have stale boxes) can't.
+func (e *Entity) CollisionActive() bool {
+	return e.Visible && e.Collider.Shape != ColliderNone && e.aabbPass == transformPass
+}
+
+// CollisionBounds returns the entity's swept box from the last
+// UpdateTransforms. With CollisionActive, this makes an Entity a
+// collision.Body.
+func (e *Entity) CollisionBounds() aabb.AABB {
+	return e.SweptAABB
+}
+
+// Collides reports whether two entities' swept boxes overlap
+func (e *Entity) Collides(other *Entity) bool {
+	if !e.CollisionActive() || !other.CollisionActive() {
+		return false
+	}
+
+	return e.SweptAABB.TestCollision(&other.SweptAABB)
+}
+
+// loadJSONCollider reads the Collider property of an entity
+func loadJSONCollider(node map[string]interface{}, filename, id string) Collider {
+	var c Collider
+
+	for k, v := range node {
+		switch k {
+		case "Shape":
+			switch v {
+			case "BOX":
+				c.Shape = ColliderBox
+			case "NONE":
+				c.Shape = ColliderNone
+			default:
+				panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unknown Collider Shape %v", filename, id, v))
+			}
+		case "Left":
+			c.Left = jsonInt32(filename, id, k, v)
+		case "Top":
+			c.Top = jsonInt32(filename, id, k, v)
+		case "Right":
+			c.Right = jsonInt32(filename, id, k, v)
+		case "Bottom":
+			c.Bottom = jsonInt32(filename, id, k, v)
+		default:
+			panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unrecognized Collider key: %s", filename, id, k))
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,241 @@
+package scenegraph
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	// If the dirty rects cover more than this fraction of the surface, it's
+	// quicker to just redraw all of it
+	dirtyFullFraction = 0.6
+
+	debugDirtyBorder = 2 // px
+)
+
+var (
+	// FullRedraw makes RenderDirty redraw the whole surface every frame, in
+	// case the dirty tracking is getting something wrong
+	FullRedraw bool
+
+	// DebugDirtyRects makes RenderDirty outline the regions it redraws
+	DebugDirtyRects bool
+)
+
+// drawnState is what an entity looked like when it was last drawn. If any of
+// it changes, the entity needs redrawing.
+type drawnState struct {
+	surface *sdl.Surface
+	m       Matrix
+	look    look
+	bounds  sdl.Rect
+}
+
+// dirtyTracker remembers the last frame for RenderDirty
+type dirtyTracker struct {
+	dest    *sdl.Surface
+	full    bool
+	prev    map[*Entity]drawnState
+	cur     map[*Entity]drawnState
+	rects   []sdl.Rect
+	flashed []sdl.Rect // outlined last frame, so they need erasing
+}
+
+// MarkDirty tells RenderDirty an entity needs redrawing even though it's in
+// the same place with the same surface, e.g. because the surface was redrawn
+// or had its alpha changed. Surfaces that are freed and re-rendered need this
+// too, since the new one might have the same address as the old.
+func (e *Entity) MarkDirty() {
+	e.dirty = true
+}
+
+// Invalidate makes the next RenderDirty redraw everything, for when something
+// else has drawn on the surface in the meantime
+func (e *Entity) Invalidate() {
+	if e.tracker != nil {
+		e.tracker.full = true
+	}
+}
+
+// drawBounds returns where draw will put the entity's surface
+func (e *Entity) drawBounds() (sdl.Rect, bool) {
+	if e.Surface == nil {
+		return sdl.Rect{}, false
+	}
+
+	m := e.EntityToWorld
+	x := int32(math.Round(m.Tx))
+	y := int32(math.Round(m.Ty))
+
+	if m.IsTranslation() {
+		return sdl.Rect{X: x, Y: y, W: e.Surface.W, H: e.Surface.H}, true
+	}
+
+	if m.Det() == 0 {
+		return sdl.Rect{}, false
+	}
+
+	x0, y0, x1, y1 := m.linear().Bounds(e.Surface.W, e.Surface.H)
+
+	return sdl.Rect{X: x + x0, Y: y + y0, W: x1 - x0, H: y1 - y0}, true
+}
+
+// addDirty adds a region to the dirty list, merging it with anything it
+// overlaps
+func (dt *dirtyTracker) addDirty(r sdl.Rect) {
+	if r.W <= 0 || r.H <= 0 {
+		return
+	}
+
+	// Merging can make the rect overlap ones it didn't before, so keep going
+	// until it settles
+	for merged := true; merged; {
+		merged = false
+
+		for i := 0; i < len(dt.rects); i++ {
+			if r.HasIntersection(&dt.rects[i]) {
+				r = r.Union(&dt.rects[i])
+
+				dt.rects[i] = dt.rects[len(dt.rects)-1]
+				dt.rects = dt.rects[:len(dt.rects)-1]
+
+				merged = true
+				break
+			}
+		}
+	}
+
+	dt.rects = append(dt.rects, r)
+}
+
+// findDirty compares this frame's render list with the last one and works out
+// what regions changed
+func (dt *dirtyTracker) findDirty(list []renderItem) {
+	for _, item := range list {
+		e := item.entity
+
+		bounds, ok := e.drawBounds()
+		if !ok {
+			continue
+		}
+
+		if bounds, ok = bounds.Intersect(&item.clip); !ok {
+			continue
+		}
+
+		state := drawnState{surface: e.Surface, m: e.EntityToWorld, look: e.look, bounds: bounds}
+		dt.cur[e] = state
+
+		prev, seen := dt.prev[e]
+		if seen {
+			delete(dt.prev, e)
+		}
+
+		if !seen || prev != state || e.dirty {
+			if seen {
+				dt.addDirty(prev.bounds)
+			}
+			dt.addDirty(bounds)
+		}
+	}
+
+	// Anything left was drawn last frame but isn't now
+	for e, prev := range dt.prev {
+		dt.addDirty(prev.bounds)
+		delete(dt.prev, e)
+	}
+
+	for _, r := range dt.flashed {
+		dt.addDirty(r)
+	}
+}
+
+// flash outlines the dirty rects
+func (dt *dirtyTracker) flash(dest *sdl.Surface) {
+	color := sdl.MapRGB(dest.Format, 255, 0, 255)
+	b := int32(debugDirtyBorder)
+
+	for _, r := range dt.rects {
+		dest.FillRects([]sdl.Rect{
+			{X: r.X, Y: r.Y, W: r.W, H: b},
+			{X: r.X, Y: r.Y + r.H - b, W: r.W, H: b},
+			{X: r.X, Y: r.Y, W: b, H: r.H},
+			{X: r.X + r.W - b, Y: r.Y, W: b, H: r.H},
+		}, color)
+	}
+
+	dt.flashed = append(dt.flashed[:0], dt.rects...)
+}
+
+// RenderDirty renders a hierarchy to the given surface like Render, but only
+// redraws the parts that changed since the last RenderDirty, filling behind
+// them with bgColor. It returns the regions it drew, suitable for
+// Window.UpdateSurfaceRects.
+//
+// Changes are found by comparing each entity's surface and transform to the
+// last frame; see MarkDirty for the ones that can't be spotted that way. If
+// too much changed, or FullRedraw is set, the whole surface is redrawn.
+func (e *Entity) RenderDirty(dest *sdl.Surface, bgColor uint32) []sdl.Rect {
+	if e.tracker == nil {
+		e.tracker = &dirtyTracker{
+			full: true,
+			prev: make(map[*Entity]drawnState),
+			cur:  make(map[*Entity]drawnState),
+		}
+	}
+
+	dt := e.tracker
+
+	var clip sdl.Rect
+	dest.GetClipRect(&clip)
+
+	list := e.buildRenderList(clip)
+
+	dt.rects = dt.rects[:0]
+	dt.findDirty(list)
+	dt.prev, dt.cur = dt.cur, dt.prev
+
+	// See if it's worth the trouble
+	var area float64
+	for _, r := range dt.rects {
+		area += float64(r.W) * float64(r.H)
+	}
+
+	if FullRedraw || dt.full || dt.dest != dest || area > dirtyFullFraction*float64(dest.W)*float64(dest.H) {
+		dt.rects = append(dt.rects[:0], sdl.Rect{X: 0, Y: 0, W: dest.W, H: dest.H})
+		dt.full = false
+		dt.dest = dest
+	}
+
+	for _, r := range dt.rects {
+		dest.SetClipRect(&r)
+		dest.FillRect(&r, bgColor)
+
+		for _, item := range list {
+			itemClip, ok := item.clip.Intersect(&r)
+			if !ok {
+				continue
+			}
+
+			dest.SetClipRect(&itemClip)
+			item.entity.draw(dest)
+		}
+	}
+
+	dest.SetClipRect(&clip)
+
+	for _, item := range list {
+		item.entity.dirty = false
+	}
+
+	if DebugDirtyRects {
+		dt.flash(dest)
+	} else {
+		dt.flashed = dt.flashed[:0]
+	}
+
+	e.releaseRenderList()
+
+	return dt.rects
+}
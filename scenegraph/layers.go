@@ -0,0 +1,143 @@
+package scenegraph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// DefaultLayers are the layers used when the root of a render doesn't declare
+// its own, from the bottom up
+var DefaultLayers = []string{"background", "gameplay", "hud", "overlay"}
+
+// renderItem is one entity in the draw order worked out by Render
+type renderItem struct {
+	entity *Entity
+	layer  int
+	z      int
+	clip   sdl.Rect // clip rect in effect for this entity
+}
+
+// layerIndex finds a layer by name, bottom layer first
+func layerIndex(layers []string, name string) int {
+	for i, l := range layers {
+		if l == name {
+			return i
+		}
+	}
+
+	panic(fmt.Sprintf("scenegraph: unknown layer %q", name))
+}
+
+// collect runs down the hierarchy working out the transforms, looks, layers,
+// Z and clipping of each visible entity, and adds them to the render list. The
+// parent is nil for the root.
+func (e *Entity) collect(list []renderItem, layers []string, parent *Entity, layer, z int, clip sdl.Rect) []renderItem {
+	// If invisible, stop processing this subtree
+	if !e.Visible {
+		return list
+	}
+
+	// save these for anyone who might want them later
+	if parent != nil {
+		e.EntityToWorld = parent.EntityToWorld.Mul(e.localMatrix())
+	} else {
+		e.EntityToWorld = e.localMatrix()
+	}
+	e.WorldToEntity = e.EntityToWorld.Inverse()
+
+	e.inheritLook(parent)
+
+	// Layers are inherited, and Z is relative to the parent's
+	if e.Layer != "" {
+		layer = layerIndex(layers, e.Layer)
+	}
+	z += e.Z
+
+	list = append(list, renderItem{entity: e, layer: layer, z: z, clip: clip})
+
+	if e.Clip {
+		bounds := e.WorldBounds()
+
+		var ok bool
+		if clip, ok = clip.Intersect(&bounds); !ok {
+			return list // nothing to see
+		}
+	}
+
+	for _, c := range e.Children {
+		list = c.collect(list, layers, e, layer, z, clip)
+	}
+
+	return list
+}
+
+// draw blits an entity's surface through its world transform
+func (e *Entity) draw(dest *sdl.Surface) {
+	if e.Surface == nil {
+		return
+	}
+
+	m := e.EntityToWorld
+
+	if m.IsTranslation() {
+		rect := sdl.Rect{X: int32(math.Round(m.Tx)), Y: int32(math.Round(m.Ty))}
+		e.blit(e.Surface, dest, &rect)
+	} else if m.Det() != 0 {
+		surface, x, y := e.transformed(m)
+		e.blit(surface, dest, &sdl.Rect{X: x, Y: y})
+	}
+}
+
+// buildRenderList works out what to draw and in what order, into the
+// entity's render list. Entities are drawn by layer, then by Z within the
+// layer. Anything that ties goes in hierarchy order, parents before children
+// and earlier children first.
+func (e *Entity) buildRenderList(clip sdl.Rect) []renderItem {
+	layers := e.Layers
+	if len(layers) == 0 {
+		layers = DefaultLayers
+	}
+
+	list := e.collect(e.renderList[:0], layers, nil, 0, 0, clip)
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].layer != list[j].layer {
+			return list[i].layer < list[j].layer
+		}
+		return list[i].z < list[j].z
+	})
+
+	for i := range list {
+		list[i].entity.drawOrder = i
+	}
+
+	e.renderList = list
+
+	return list
+}
+
+// releaseRenderList keeps the list around so the next frame doesn't have to
+// grow it again, but doesn't hang on to the entities
+func (e *Entity) releaseRenderList() {
+	for i := range e.renderList {
+		e.renderList[i].entity = nil
+	}
+}
+
+// Render renders a hierarchy to the given surface, by layer and Z
+func (e *Entity) Render(dest *sdl.Surface) {
+	var clip sdl.Rect
+	dest.GetClipRect(&clip)
+
+	for _, item := range e.buildRenderList(clip) {
+		dest.SetClipRect(&item.clip)
+		item.entity.draw(dest)
+	}
+
+	dest.SetClipRect(&clip)
+
+	e.releaseRenderList()
+}
@@ -0,0 +1,126 @@
+package scenegraph
+
+import "math"
+
+// Matrix is a 2D affine transform, the top two rows of a 3x3 matrix:
+//
+//	| A C Tx |
+//	| B D Ty |
+//	| 0 0 1  |
+//
+// Points are column vectors, so m.Mul(n) applies n first, then m.
+type Matrix struct {
+	A, B, C, D, Tx, Ty float64
+}
+
+// Identity returns the do-nothing transform
+func Identity() Matrix {
+	return Matrix{A: 1, D: 1}
+}
+
+// Translation returns a transform that moves by x, y
+func Translation(x, y float64) Matrix {
+	return Matrix{A: 1, D: 1, Tx: x, Ty: y}
+}
+
+// Scaling returns a transform that scales by sx, sy about the origin
+func Scaling(sx, sy float64) Matrix {
+	return Matrix{A: sx, D: sy}
+}
+
+// Rotation returns a transform that rotates by deg degrees about the origin.
+// Y goes down the screen, so positive angles are clockwise.
+func Rotation(deg float64) Matrix {
+	s, c := math.Sincos(deg * math.Pi / 180)
+
+	return Matrix{A: c, B: s, C: -s, D: c}
+}
+
+// Mul returns m·n, the transform that does n and then m
+func (m Matrix) Mul(n Matrix) Matrix {
+	return Matrix{
+		A:  m.A*n.A + m.C*n.B,
+		B:  m.B*n.A + m.D*n.B,
+		C:  m.A*n.C + m.C*n.D,
+		D:  m.B*n.C + m.D*n.D,
+		Tx: m.A*n.Tx + m.C*n.Ty + m.Tx,
+		Ty: m.B*n.Tx + m.D*n.Ty + m.Ty,
+	}
+}
+
+// Apply transforms a point
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m.A*x + m.C*y + m.Tx, m.B*x + m.D*y + m.Ty
+}
+
+// ApplyVector transforms a direction, ignoring the translation
+func (m Matrix) ApplyVector(x, y float64) (float64, float64) {
+	return m.A*x + m.C*y, m.B*x + m.D*y
+}
+
+// Det returns the determinant. Zero means the transform squashes everything
+// flat and can't be inverted.
+func (m Matrix) Det() float64 {
+	return m.A*m.D - m.B*m.C
+}
+
+// Inverse returns the transform that undoes m. A singular matrix comes back as
+// all zeros, which sends every point to the origin.
+func (m Matrix) Inverse() Matrix {
+	det := m.Det()
+	if det == 0 {
+		return Matrix{}
+	}
+
+	a := m.D / det
+	b := -m.B / det
+	c := -m.C / det
+	d := m.A / det
+
+	return Matrix{
+		A: a, B: b, C: c, D: d,
+		Tx: -(a*m.Tx + c*m.Ty),
+		Ty: -(b*m.Tx + d*m.Ty),
+	}
+}
+
+// IsTranslation reports whether the transform only moves things, in which case
+// surfaces can be blitted as-is
+func (m Matrix) IsTranslation() bool {
+	return m.A == 1 && m.B == 0 && m.C == 0 && m.D == 1
+}
+
+// linear returns the transform without its translation
+func (m Matrix) linear() Matrix {
+	m.Tx = 0
+	m.Ty = 0
+
+	return m
+}
+
+// Bounds returns the axis-aligned box, in whole pixels, around a w by h
+// rectangle at the origin after it's been transformed
+func (m Matrix) Bounds(w, h int32) (x0, y0, x1, y1 int32) {
+	return m.BoundsRect(0, 0, w, h)
+}
+
+// BoundsRect returns the axis-aligned box, in whole pixels, around the
+// rectangle from (rx0, ry0) to (rx1, ry1) after it's been transformed
+func (m Matrix) BoundsRect(rx0, ry0, rx1, ry1 int32) (x0, y0, x1, y1 int32) {
+	fx0, fy0 := float64(rx0), float64(ry0)
+	fx1, fy1 := float64(rx1), float64(ry1)
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, p := range [4][2]float64{{fx0, fy0}, {fx1, fy0}, {fx0, fy1}, {fx1, fy1}} {
+		x, y := m.Apply(p[0], p[1])
+
+		minX = math.Min(minX, x)
+		minY = math.Min(minY, y)
+		maxX = math.Max(maxX, x)
+		maxY = math.Max(maxY, y)
+	}
+
+	return int32(math.Floor(minX)), int32(math.Floor(minY)), int32(math.Ceil(maxX)), int32(math.Ceil(maxY))
+}
@@ -0,0 +1,252 @@
+package scenegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/beejjorgensen/eggdrop/assetmanager"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// EmitterPreset describes the particles an Emitter makes. The Var fields are
+// how far each particle can randomly stray from the value, either way.
+type EmitterPreset struct {
+	Surfaces []*sdl.Surface `json:"-"` // each particle gets one at random
+
+	Max   int     // most particles alive at once
+	Burst int     // particles per Burst(0)
+	Rate  float64 // particles/s while Emitting
+
+	Lifetime, LifetimeVar uint32  // ms
+	Speed, SpeedVar       float64 // px/s
+	Angle, Spread         float64 // degrees; 0 is right, -90 is up. Spread is the whole cone.
+
+	GravityX, GravityY float64 // px/s²
+
+	Fade bool // fade out over the lifetime
+}
+
+// particle is one pooled particle
+type particle struct {
+	entity *Entity
+	x, y   float64 // center, px
+	vx, vy float64 // px/s
+	age    uint32  // ms
+	life   uint32  // ms
+}
+
+// Emitter is an entity that makes particles. The particles are its children,
+// all made up front, so emitting doesn't allocate anything.
+type Emitter struct {
+	*Entity
+
+	Preset *EmitterPreset
+
+	// Where the particles come from, in the emitter's coordinates
+	OriginX, OriginY float64
+
+	// Emitting makes particles continuously at the preset Rate
+	Emitting bool
+
+	particles []particle
+	next      int     // where to start looking for a free particle
+	owed      float64 // fractional particles carried over between updates
+	rng       *rand.Rand
+}
+
+// NewEmitter makes an emitter with a pool of particles. Particles are just for
+// looks, so they have their own random numbers and leave the game's alone.
+func NewEmitter(preset *EmitterPreset) *Emitter {
+	em := &Emitter{
+		Entity:    NewEntity(nil),
+		Preset:    preset,
+		particles: make([]particle, preset.Max),
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for i := range em.particles {
+		e := NewEntity(nil)
+		e.Visible = false
+
+		em.particles[i].entity = e
+		em.AddChild(e)
+	}
+
+	return em
+}
+
+// vary returns a value randomly up to v either side of base
+func (em *Emitter) vary(base, v float64) float64 {
+	return base + (em.rng.Float64()*2-1)*v
+}
+
+// spawn brings a free particle to life. If they're all busy, nothing happens.
+func (em *Emitter) spawn() {
+	p := em.Preset
+	n := len(em.particles)
+
+	for i := 0; i < n; i++ {
+		pt := &em.particles[(em.next+i)%n]
+		if pt.entity.Visible {
+			continue
+		}
+
+		em.next = (em.next + i + 1) % n
+
+		e := pt.entity
+		e.Surface = p.Surfaces[em.rng.Intn(len(p.Surfaces))]
+		e.W = e.Surface.W
+		e.H = e.Surface.H
+		e.Alpha = 255
+		e.Visible = true
+
+		angle := em.vary(p.Angle, p.Spread/2) * math.Pi / 180
+		speed := em.vary(p.Speed, p.SpeedVar)
+
+		pt.x = em.OriginX
+		pt.y = em.OriginY
+		pt.vx = math.Cos(angle) * speed
+		pt.vy = math.Sin(angle) * speed
+		pt.age = 0
+		pt.life = uint32(math.Max(1, em.vary(float64(p.Lifetime), float64(p.LifetimeVar))))
+
+		em.place(pt)
+
+		return
+	}
+}
+
+// place moves a particle's entity to its position
+func (em *Emitter) place(pt *particle) {
+	e := pt.entity
+
+	e.X = int32(pt.x) - e.W/2
+	e.Y = int32(pt.y) - e.H/2
+
+	if em.Preset.Fade {
+		e.Alpha = uint8(255 - 255*pt.age/pt.life)
+	}
+}
+
+// Burst makes a bunch of particles at once at the origin. Zero means the
+// preset's Burst count.
+func (em *Emitter) Burst(count int) {
+	if count <= 0 {
+		count = em.Preset.Burst
+	}
+
+	for i := 0; i < count; i++ {
+		em.spawn()
+	}
+}
+
+// BurstAt moves the origin and makes a burst there. Particles already in
+// flight aren't affected.
+func (em *Emitter) BurstAt(x, y float64, count int) {
+	em.OriginX = x
+	em.OriginY = y
+
+	em.Burst(count)
+}
+
+// Update emits, moves, fades and retires particles for dt ms passing
+func (em *Emitter) Update(dt uint32) {
+	p := em.Preset
+	secs := float64(dt) / 1000
+
+	if em.Emitting && p.Rate > 0 {
+		em.owed += p.Rate * secs
+		for ; em.owed >= 1; em.owed-- {
+			em.spawn()
+		}
+	}
+
+	for i := range em.particles {
+		pt := &em.particles[i]
+		if !pt.entity.Visible {
+			continue
+		}
+
+		pt.age += dt
+		if pt.age >= pt.life {
+			pt.entity.Visible = false
+			continue
+		}
+
+		pt.vx += p.GravityX * secs
+		pt.vy += p.GravityY * secs
+		pt.x += pt.vx * secs
+		pt.y += pt.vy * secs
+
+		em.place(pt)
+	}
+}
+
+// Reset gets rid of all the particles and stops emitting
+func (em *Emitter) Reset() {
+	em.Emitting = false
+	em.owed = 0
+
+	for i := range em.particles {
+		em.particles[i].entity.Visible = false
+	}
+}
+
+// jsonEmitter is an emitter preset in the "Emitters" JSON
+type jsonEmitter struct {
+	ID     string `json:"Id"`
+	Assets []string
+	EmitterPreset
+}
+
+// LoadEmitterPresets reads the "Emitters" section of an asset JSON file. The
+// surfaces they refer to must already be loaded into the asset manager.
+func LoadEmitterPresets(am *assetmanager.AssetManager, jsonFile string) (map[string]*EmitterPreset, error) {
+	jsonStr, err := ioutil.ReadFile(assetmanager.AssetPath(jsonFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonRoot struct {
+		Emitters []jsonEmitter
+	}
+
+	if err = json.Unmarshal(jsonStr, &jsonRoot); err != nil {
+		return nil, err
+	}
+
+	presets := make(map[string]*EmitterPreset)
+
+	for i := range jsonRoot.Emitters {
+		je := &jsonRoot.Emitters[i]
+		p := &je.EmitterPreset
+
+		if len(je.Assets) == 0 {
+			return nil, fmt.Errorf("%s: no Assets", je.ID)
+		}
+
+		for _, asset := range je.Assets {
+			surface, ok := am.Surfaces[asset]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown asset %q", je.ID, asset)
+			}
+			p.Surfaces = append(p.Surfaces, surface)
+		}
+
+		if p.Max <= 0 {
+			return nil, fmt.Errorf("%s: Max must be positive", je.ID)
+		}
+
+		if p.Lifetime == 0 {
+			return nil, fmt.Errorf("%s: Lifetime must be positive", je.ID)
+		}
+
+		presets[je.ID] = p
+	}
+
+	return presets, nil
+}
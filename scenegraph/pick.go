@@ -0,0 +1,127 @@
+package scenegraph
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Contains reports whether a point in world coordinates is inside the entity.
+// It goes by where the entity was last rendered, so it's only meaningful after
+// a Render. Scaled and rotated entities are tested against their actual
+// shape, not the box around it.
+func (e *Entity) Contains(x, y int32) bool {
+	if e.EntityToWorld.Det() == 0 {
+		return false // squashed flat, or never rendered
+	}
+
+	// Test the middle of the pixel, same as rendering samples it
+	lx, ly := e.WorldToEntity.Apply(float64(x)+0.5, float64(y)+0.5)
+
+	return lx >= 0 && lx < float64(e.W) && ly >= 0 && ly < float64(e.H)
+}
+
+// Pick returns the topmost visible entity in the hierarchy at a point in
+// world coordinates, or nil if there isn't one. Topmost goes by the order of
+// the last Render, so layers and Z are taken into account.
+func (e *Entity) Pick(x, y int32) *Entity {
+	return e.PickFunc(x, y, nil)
+}
+
+// PickFunc is like Pick, but only entities that accept returns true for are
+// considered. The others are looked through to whatever is below. A nil
+// accept takes everything.
+func (e *Entity) PickFunc(x, y int32, accept func(*Entity) bool) *Entity {
+	return e.pick(x, y, accept, nil)
+}
+
+// pick runs down the hierarchy looking for something drawn later than best
+func (e *Entity) pick(x, y int32, accept func(*Entity) bool, best *Entity) *Entity {
+	if !e.Visible {
+		return best
+	}
+
+	if e.Contains(x, y) && (accept == nil || accept(e)) {
+		// Ties go to the later one in the hierarchy, same as drawing
+		if best == nil || e.drawOrder >= best.drawOrder {
+			best = e
+		}
+	}
+
+	if e.Clip && !e.Contains(x, y) {
+		return best
+	}
+
+	for _, c := range e.Children {
+		best = c.pick(x, y, accept, best)
+	}
+
+	return best
+}
+
+// hasMouseHandler reports whether an entity wants to hear about the mouse
+func hasMouseHandler(e *Entity) bool {
+	return e.OnMouseEnter != nil || e.OnMouseLeave != nil || e.OnClick != nil
+}
+
+// MouseTracker delivers OnMouseEnter, OnMouseLeave and OnClick to the
+// entities in a hierarchy. Only entities with at least one of those set are
+// tracked; anything else is looked through.
+type MouseTracker struct {
+	hover *Entity
+}
+
+// setHover moves the hover to a new entity, telling the old and new ones
+func (mt *MouseTracker) setHover(e *Entity) {
+	if e == mt.hover {
+		return
+	}
+
+	if mt.hover != nil && mt.hover.OnMouseLeave != nil {
+		mt.hover.OnMouseLeave(mt.hover)
+	}
+
+	mt.hover = e
+
+	if e != nil && e.OnMouseEnter != nil {
+		e.OnMouseEnter(e)
+	}
+}
+
+// Move moves the mouse to a point in world coordinates, calling
+// OnMouseLeave and OnMouseEnter if it's gone from one entity to another
+func (mt *MouseTracker) Move(root *Entity, x, y int32) {
+	mt.setHover(root.PickFunc(x, y, hasMouseHandler))
+}
+
+// Click clicks at a point in world coordinates. It returns true if an entity
+// took the click.
+func (mt *MouseTracker) Click(root *Entity, x, y int32) bool {
+	e := root.PickFunc(x, y, hasMouseHandler)
+	mt.setHover(e)
+
+	if e != nil && e.OnClick != nil {
+		e.OnClick(e)
+		return true
+	}
+
+	return false
+}
+
+// HandleEvent looks at mouse events and calls the appropriate entity
+// callbacks. It returns true if an entity took a click.
+func (mt *MouseTracker) HandleEvent(root *Entity, event *sdl.Event) bool {
+	switch event := (*event).(type) {
+	case *sdl.MouseMotionEvent:
+		mt.Move(root, event.X, event.Y)
+
+	case *sdl.MouseButtonEvent:
+		if event.Type == sdl.MOUSEBUTTONDOWN {
+			return mt.Click(root, event.X, event.Y)
+		}
+	}
+
+	return false
+}
+
+// Reset forgets the hovered entity without calling OnMouseLeave, for when
+// the hierarchy goes away or gets rebuilt
+func (mt *MouseTracker) Reset() {
+	mt.hover = nil
+}
@@ -1,8 +1,9 @@
 // Package scenegraph is a simple hierarchical scene graph, and declares an
 // Entity type that associates an entity with a surface.
 //
-// The EntityTransform type is currently cheesy, but could eventually be
-// replaced by something more appropriate (e.g. a matrix).
+// Each entity's EntityTransform is turned into an affine Matrix and composed
+// down the hierarchy, so children move, scale, rotate and flip along with
+// their parents.
 package scenegraph
 
 import (
@@ -18,21 +19,72 @@ import (
 
 const initialChildrenCap = 5
 
-// EntityTransform holds Entity transform information.
-// Maybe someday this will grow up to be some kind of matrix.
+// EntityTransform holds Entity transform information. X and Y are the position
+// in the parent and W and H the unscaled size.
+//
+// Scaling and rotation (in degrees, clockwise) happen about the pivot, which
+// is in entity coordinates; (0, 0) is the top left. The flips mirror the
+// entity within its own W by H box before anything else.
 type EntityTransform struct {
-	X, Y, W, H int32
+	X, Y, W, H     int32
+	ScaleX, ScaleY float64
+	Rotation       float64
+	PivotX, PivotY float64
+	FlipH, FlipV   bool
 }
 
 // Entity is a graphics entity with associated surface
 type Entity struct {
 	EntityTransform
-	EntityToWorld, WorldToEntity EntityTransform
-	Surface                      *sdl.Surface
-	Children                     []*Entity
-	Visible                      bool
-	MoveAABB                     aabb.AABB
-	ID                           string
+
+	// The accumulated transforms as of the last Render
+	EntityToWorld, WorldToEntity Matrix
+
+	Surface  *sdl.Surface
+	Children []*Entity
+	Visible  bool
+	ID       string
+
+	// Collision bounds in world coordinates, kept up to date by
+	// UpdateTransforms
+	Collider             Collider
+	WorldAABB, SweptAABB aabb.AABB
+
+	// Layer names the layer this entity and its children draw in; empty
+	// means the same as the parent. Z orders entities within a layer, higher
+	// on top, and adds up down the hierarchy.
+	Layer string
+	Z     int
+
+	// Alpha and ColorMod are applied when blitting, on top of whatever the
+	// surface itself has set, and multiply down the hierarchy. Blend is one
+	// of the Blend constants, and is inherited unless it's set.
+	Alpha    uint8
+	ColorMod sdl.Color
+	Blend    int
+
+	// Anim, if set, swaps the surface to animate the entity
+	Anim *Animation
+
+	// Layers, on the root of a render, lists the layer names from the
+	// bottom up. If it's empty, DefaultLayers are used.
+	Layers []string
+
+	// Clip keeps children from drawing (or being picked) outside this
+	// entity's bounds. Drawing is clipped to the box around a rotated entity,
+	// since SDL clip rects can't turn.
+	Clip bool
+
+	// Mouse callbacks, delivered by a MouseTracker
+	OnMouseEnter, OnMouseLeave, OnClick func(e *Entity)
+
+	transformCache transformCache
+	renderList     []renderItem
+	drawOrder      int // where it was drawn in the last Render
+	dirty          bool
+	look           look          // Alpha, ColorMod and Blend as of the last Render
+	tracker        *dirtyTracker // on the root, for RenderDirty
+	aabbPass       uint32        // the UpdateTransforms pass the AABBs are from
 }
 
 // NewEntity creates a new Entity for a given surface (or nil)
@@ -41,9 +93,13 @@ func NewEntity(surface *sdl.Surface) *Entity {
 		Surface:  surface,
 		Children: make([]*Entity, 0, initialChildrenCap),
 		Visible:  true,
-		MoveAABB: aabb.AABB{},
 	}
 
+	e.ScaleX = 1
+	e.ScaleY = 1
+	e.Alpha = 255
+	e.ColorMod = sdl.Color{R: 255, G: 255, B: 255, A: 255}
+
 	if surface != nil {
 		e.W = surface.W
 		e.H = surface.H
@@ -72,52 +128,53 @@ func (e *Entity) GetChild(index int) *Entity {
 	return e.Children[index]
 }
 
-// MoveTo updates an entity position, tracking the move AABB
+// MoveTo updates an entity position. The swept bounds for collisions are
+// worked out by UpdateTransforms, so it's no different from setting X and Y.
 func (e *Entity) MoveTo(x, y int32) {
-	mab := &e.MoveAABB
-
-	mab.X0 = e.X
-	mab.Y0 = e.Y
-	mab.X1 = e.X + e.W
-	mab.Y1 = e.Y + e.H
-
-	mab.Expand(x, y)
-	mab.Expand(x+e.W, y+e.H)
-
 	e.X = x
 	e.Y = y
 }
 
-// Internal render call
-func (e *Entity) renderRecursive(dest *sdl.Surface, t EntityTransform) {
-	// If invisible, stop processing this subtree
-	if !e.Visible {
-		return
-	}
+// WorldBounds returns the axis-aligned box around the entity in world
+// coordinates, as of the last Render
+func (e *Entity) WorldBounds() sdl.Rect {
+	x0, y0, x1, y1 := e.EntityToWorld.Bounds(e.W, e.H)
 
-	// save these for anyone who might want them later
-	e.EntityToWorld.X = e.X + t.X
-	e.EntityToWorld.Y = e.Y + t.Y
-	e.WorldToEntity.X = -e.EntityToWorld.X // invert
-	e.WorldToEntity.Y = -e.EntityToWorld.Y
+	return sdl.Rect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
 
-	rect := sdl.Rect{X: e.X + t.X, Y: e.Y + t.Y}
-	e.Surface.Blit(nil, dest, &rect)
+// jsonFloat gets a number out of a JSON property
+func jsonFloat(filename, id, key string, v interface{}) float64 {
+	f, ok := v.(float64)
+	if !ok {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: %s must be a number", filename, id, key))
+	}
 
-	t.X += e.X
-	t.Y += e.Y
+	return f
+}
 
-	for _, c := range e.Children {
-		c.renderRecursive(dest, t)
+// jsonByte gets a 0-255 value out of a JSON property
+func jsonByte(filename, id, key string, v interface{}) uint8 {
+	f := jsonFloat(filename, id, key, v)
+	if f < 0 || f > 255 {
+		panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: %s must be 0-255", filename, id, key))
 	}
+
+	return uint8(f)
 }
 
-// Render renders a hierarchy to the given surface
-func (e *Entity) Render(dest *sdl.Surface) {
+// jsonPivot gets a pivot coordinate, which may be a number or "CENTER" for
+// the middle of the given size
+func jsonPivot(filename, id, key string, v interface{}, size int32) float64 {
+	if s, ok := v.(string); ok {
+		if s != "CENTER" {
+			panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unknown %s %q", filename, id, key, s))
+		}
 
-	t := EntityTransform{0, 0, dest.W, dest.H}
+		return float64(size) / 2
+	}
 
-	e.renderRecursive(dest, t)
+	return jsonFloat(filename, id, key, v)
 }
 
 // parseJSONPos
@@ -160,14 +217,53 @@ func loadJSONRecursive(am *assetmanager.AssetManager, node map[string]interface{
 	// Now do the rest of the properties
 	for k, v := range node {
 		switch k {
-		case "Id", "W", "H", "Asset":
-			// do nothing; handled above
+		case "Id", "W", "H", "Asset", "Animation":
+			// do nothing; handled above or below
 		case "X":
 			entity.X = am.ParsePosition(filename, id, entity.W, "X", node)
 		case "Y":
 			entity.Y = am.ParsePosition(filename, id, entity.H, "Y", node)
 		case "Visible":
 			entity.Visible = v.(bool)
+		case "Alpha":
+			entity.Alpha = jsonByte(filename, id, k, v)
+		case "ColorMod":
+			rgb, ok := v.([]interface{})
+			if !ok || len(rgb) != 3 {
+				panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: ColorMod must be [R,G,B]", filename, id))
+			}
+			entity.ColorMod.R = jsonByte(filename, id, k, rgb[0])
+			entity.ColorMod.G = jsonByte(filename, id, k, rgb[1])
+			entity.ColorMod.B = jsonByte(filename, id, k, rgb[2])
+		case "Blend":
+			blend, ok := blendNames[v.(string)]
+			if !ok {
+				panic(fmt.Sprintf("scenegraph.LoadJSON: %s: %s: unknown Blend %q", filename, id, v))
+			}
+			entity.Blend = blend
+		case "Collider":
+			entity.Collider = loadJSONCollider(v.(map[string]interface{}), filename, id)
+		case "Layer":
+			entity.Layer = v.(string)
+		case "Z":
+			entity.Z = int(jsonFloat(filename, id, k, v))
+		case "Scale":
+			entity.ScaleX = jsonFloat(filename, id, k, v)
+			entity.ScaleY = entity.ScaleX
+		case "ScaleX":
+			entity.ScaleX = jsonFloat(filename, id, k, v)
+		case "ScaleY":
+			entity.ScaleY = jsonFloat(filename, id, k, v)
+		case "Rotation":
+			entity.Rotation = jsonFloat(filename, id, k, v)
+		case "PivotX":
+			entity.PivotX = jsonPivot(filename, id, k, v, entity.W)
+		case "PivotY":
+			entity.PivotY = jsonPivot(filename, id, k, v, entity.H)
+		case "FlipH":
+			entity.FlipH = v.(bool)
+		case "FlipV":
+			entity.FlipV = v.(bool)
 		case "Children":
 			for _, child := range v.([]interface{}) {
 				childEntity = loadJSONRecursive(am, child.(map[string]interface{}), filename, entityByID)
@@ -178,6 +274,11 @@ func loadJSONRecursive(am *assetmanager.AssetManager, node map[string]interface{
 		}
 	}
 
+	// Last, since the first frame's offset goes on top of X and Y
+	if anim, ok := node["Animation"].(map[string]interface{}); ok {
+		loadJSONAnimation(am, entity, anim, filename, id)
+	}
+
 	return entity
 }
 
@@ -199,9 +300,48 @@ func LoadJSON(am *assetmanager.AssetManager, jsonFile string, entityByID map[str
 
 	root := loadJSONRecursive(am, sceneRoot, jsonFile, entityByID)
 
+	if layers, ok := jsonRoot["Layers"].([]interface{}); ok {
+		for _, l := range layers {
+			name, ok := l.(string)
+			if !ok {
+				return nil, errors.New("Layers must be an array of names")
+			}
+			root.Layers = append(root.Layers, name)
+		}
+	}
+
+	if err = root.checkLayers(root.Layers); err != nil {
+		return nil, err
+	}
+
 	return root, nil
 }
 
+// checkLayers makes sure every entity in the hierarchy is in a known layer
+func (e *Entity) checkLayers(layers []string) error {
+	if len(layers) == 0 {
+		layers = DefaultLayers
+	}
+
+	if e.Layer != "" {
+		found := false
+		for _, l := range layers {
+			found = found || l == e.Layer
+		}
+		if !found {
+			return fmt.Errorf("%s: unknown Layer %q", e.ID, e.Layer)
+		}
+	}
+
+	for _, c := range e.Children {
+		if err := c.checkLayers(layers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // SearchByID returns the entity with the matching ID, or nil. Slow. Pass
 // entityByID map into LoadJSON for quicker results.
 func (e *Entity) SearchByID(id string) *Entity {
@@ -0,0 +1,144 @@
+package scenegraph
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// transformCache holds the last transformed copy of an entity's surface so it
+// doesn't have to be redone every frame. It's only valid for the same source
+// surface and the same scale/rotation/flip; moving around doesn't matter.
+type transformCache struct {
+	src     *sdl.Surface
+	linear  Matrix
+	surface *sdl.Surface
+	x0, y0  int32 // offset of the transformed surface from the entity origin
+}
+
+// free releases the cached surface
+func (tc *transformCache) free() {
+	if tc.surface != nil {
+		tc.surface.Free()
+	}
+
+	*tc = transformCache{}
+}
+
+// localMatrix builds the entity's own transform: flip within its box, then
+// scale and rotate about the pivot, then move into place in the parent
+func (t *EntityTransform) localMatrix() Matrix {
+	m := Translation(float64(t.X)+t.PivotX, float64(t.Y)+t.PivotY)
+
+	if t.Rotation != 0 {
+		m = m.Mul(Rotation(t.Rotation))
+	}
+
+	if t.ScaleX != 1 || t.ScaleY != 1 {
+		m = m.Mul(Scaling(t.ScaleX, t.ScaleY))
+	}
+
+	m = m.Mul(Translation(-t.PivotX, -t.PivotY))
+
+	if t.FlipH {
+		m = m.Mul(Matrix{A: -1, D: 1, Tx: float64(t.W)})
+	}
+
+	if t.FlipV {
+		m = m.Mul(Matrix{A: 1, D: -1, Ty: float64(t.H)})
+	}
+
+	return m
+}
+
+// transformSurface makes a new surface with src drawn through the linear part
+// of m. The result is in RGBA8888 with per-pixel alpha, and is offset from the
+// origin by x0, y0. Sampling is nearest-neighbor to keep the pixel art crisp.
+func transformSurface(src *sdl.Surface, m Matrix) (surface *sdl.Surface, x0, y0 int32, err error) {
+	m = m.linear()
+	x0, y0, x1, y1 := m.Bounds(src.W, src.H)
+
+	w := x1 - x0
+	h := y1 - y0
+
+	// Get the source in a known format; this also turns any color key into
+	// alpha
+	rgba, err := src.ConvertFormat(sdl.PIXELFORMAT_RGBA8888, 0)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer rgba.Free()
+
+	pf := rgba.Format
+
+	surface, err = sdl.CreateRGBSurface(0, w, h, 32, pf.Rmask, pf.Gmask, pf.Bmask, pf.Amask)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	inv := m.Inverse()
+
+	rgba.Lock()
+	surface.Lock()
+
+	srcPx := rgba.Pixels()
+	destPx := surface.Pixels()
+
+	for y := int32(0); y < h; y++ {
+		for x := int32(0); x < w; x++ {
+			// Sample from the middle of the pixel
+			sx, sy := inv.Apply(float64(x0+x)+0.5, float64(y0+y)+0.5)
+
+			ix := int32(math.Floor(sx))
+			iy := int32(math.Floor(sy))
+
+			if ix < 0 || iy < 0 || ix >= src.W || iy >= src.H {
+				continue // leave it transparent
+			}
+
+			si := iy*rgba.Pitch + ix*4
+			di := y*surface.Pitch + x*4
+
+			copy(destPx[di:di+4], srcPx[si:si+4])
+		}
+	}
+
+	surface.Unlock()
+	rgba.Unlock()
+
+	return surface, x0, y0, nil
+}
+
+// transformed returns the entity's surface as seen through world matrix m,
+// and where to put its top left corner
+func (e *Entity) transformed(m Matrix) (*sdl.Surface, int32, int32) {
+	tc := &e.transformCache
+	linear := m.linear()
+
+	if tc.src != e.Surface || tc.linear != linear {
+		tc.free()
+
+		surface, x0, y0, err := transformSurface(e.Surface, linear)
+		if err != nil {
+			panic(fmt.Sprintf("scenegraph: transform %s: %v", e.ID, err))
+		}
+
+		tc.src = e.Surface
+		tc.linear = linear
+		tc.surface = surface
+		tc.x0 = x0
+		tc.y0 = y0
+	}
+
+	x := int32(math.Round(m.Tx)) + tc.x0
+	y := int32(math.Round(m.Ty)) + tc.y0
+
+	return tc.surface, x, y
+}
+
+// FreeTransformCache releases the cached transformed surface, if any. Call it
+// when an entity that was scaled, rotated or flipped goes away for good.
+func (e *Entity) FreeTransformCache() {
+	e.transformCache.free()
+}
@@ -0,0 +1,94 @@
+package scenegraph
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Tween moves a value from one number to another over time, handing each step
+// to a setter. Call Update every frame while it's running.
+type Tween struct {
+	From, To float64
+	Duration uint32 // ms
+
+	// Ease maps 0-1 progress to 0-1 of the way from From to To; nil is
+	// linear
+	Ease func(t float64) float64
+
+	Set func(v float64)
+
+	start   uint32
+	running bool
+}
+
+// EaseOut starts quickly and slows down at the end
+func EaseOut(t float64) float64 {
+	return 1 - (1-t)*(1-t)
+}
+
+// NewTween makes a tween. It doesn't do anything until it's started.
+func NewTween(from, to float64, duration uint32, set func(v float64)) *Tween {
+	return &Tween{From: from, To: to, Duration: duration, Set: set}
+}
+
+// AlphaTween fades an entity's Alpha
+func AlphaTween(e *Entity, from, to uint8, duration uint32) *Tween {
+	return NewTween(float64(from), float64(to), duration, func(v float64) {
+		e.Alpha = uint8(v + 0.5)
+	})
+}
+
+// ColorModTween shifts an entity's ColorMod from one color to another, e.g.
+// from red back to white for a hit flash
+func ColorModTween(e *Entity, from, to sdl.Color, duration uint32) *Tween {
+	return NewTween(0, 1, duration, func(v float64) {
+		lerp := func(a, b uint8) uint8 {
+			return uint8(float64(a) + (float64(b)-float64(a))*v + 0.5)
+		}
+
+		e.ColorMod = sdl.Color{
+			R: lerp(from.R, to.R),
+			G: lerp(from.G, to.G),
+			B: lerp(from.B, to.B),
+			A: 255,
+		}
+	})
+}
+
+// Start (re)starts the tween from the beginning, setting the From value
+func (t *Tween) Start() {
+	t.start = sdl.GetTicks()
+	t.running = true
+	t.Set(t.From)
+}
+
+// Finish jumps to the end, setting the To value
+func (t *Tween) Finish() {
+	t.running = false
+	t.Set(t.To)
+}
+
+// Running reports whether the tween hasn't reached the end yet
+func (t *Tween) Running() bool {
+	return t.running
+}
+
+// Update moves the value along for the current time. It returns false once
+// the tween is done.
+func (t *Tween) Update() bool {
+	if !t.running {
+		return false
+	}
+
+	elapsed := sdl.GetTicks() - t.start
+	if elapsed >= t.Duration {
+		t.Finish()
+		return false
+	}
+
+	p := float64(elapsed) / float64(t.Duration)
+	if t.Ease != nil {
+		p = t.Ease(p)
+	}
+
+	t.Set(t.From + (t.To-t.From)*p)
+
+	return true
+}
@@ -109,3 +109,63 @@ func SurfaceFlipV(src *sdl.Surface) (*sdl.Surface, error) {
 		}
 	})
 }
+
+// Mouse capture modes for SetMouseCapture
+const (
+	MouseCaptureNone     = iota // free cursor
+	MouseCaptureGrab            // cursor hidden and confined to the window
+	MouseCaptureRelative        // SDL relative mode: hidden, unbounded motion
+)
+
+// SetMouseCapture grabs or releases the mouse pointer for the given window.
+// The cursor is hidden in any of the capture modes.
+func SetMouseCapture(window *sdl.Window, mode int) {
+	switch mode {
+	case MouseCaptureRelative:
+		window.SetGrab(false)
+		sdl.SetRelativeMouseMode(true)
+		sdl.ShowCursor(sdl.DISABLE)
+
+	case MouseCaptureGrab:
+		sdl.SetRelativeMouseMode(false)
+		window.SetGrab(true)
+		sdl.ShowCursor(sdl.DISABLE)
+
+	default:
+		sdl.SetRelativeMouseMode(false)
+		window.SetGrab(false)
+		sdl.ShowCursor(sdl.ENABLE)
+	}
+}
+
+// controllers are the open game controllers
+var controllers []*sdl.GameController
+
+// OpenControllers opens every attached game controller, closing any that
+// were open before. SDL only sends controller button events for open ones.
+func OpenControllers() {
+	for _, c := range controllers {
+		c.Close()
+	}
+	controllers = controllers[:0]
+
+	for i := 0; i < sdl.NumJoysticks(); i++ {
+		if !sdl.IsGameController(i) {
+			continue
+		}
+
+		if c := sdl.GameControllerOpen(i); c != nil {
+			controllers = append(controllers, c)
+		}
+	}
+}
+
+// HandleControllerDeviceEvent keeps the open controllers up to date as they're
+// plugged in and pulled out. Reopening the lot is simpler than matching up
+// device indexes and instance IDs, and it doesn't happen often.
+func HandleControllerDeviceEvent(event *sdl.ControllerDeviceEvent) {
+	switch event.Type {
+	case sdl.CONTROLLERDEVICEADDED, sdl.CONTROLLERDEVICEREMOVED:
+		OpenControllers()
+	}
+}